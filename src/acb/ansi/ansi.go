@@ -0,0 +1,361 @@
+// Package ansi strips and interprets the ANSI escape sequences which programs
+// such as npm, gradle or pytest embed in their output, and measures the
+// display width of text on a terminal.
+package ansi
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const esc = 0x1b
+
+// Color is either one of the 256 indexed terminal colors, or a 24-bit color
+// if Index is -1.
+type Color struct {
+	Index   int
+	R, G, B uint8
+}
+
+// basic holds the xterm default RGB values of the 16 basic colors.
+var basic = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// RGB returns the 24-bit value of the color, using the xterm defaults for
+// indexed colors.
+func (c Color) RGB() (r, g, b uint8) {
+	switch {
+	case c.Index < 0:
+		return c.R, c.G, c.B
+	case c.Index < 16:
+		return basic[c.Index][0], basic[c.Index][1], basic[c.Index][2]
+	case c.Index < 232:
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		i := c.Index - 16
+		return levels[i/36], levels[(i/6)%6], levels[i%6]
+	default:
+		v := uint8(8 + 10*(c.Index-232))
+		return v, v, v
+	}
+}
+
+// Style is the graphic rendition in effect for a segment of text.
+type Style struct {
+	Fg, Bg    *Color
+	Bold      bool
+	Underline bool
+}
+
+// Segment is a run of text which shares the same style.
+type Segment struct {
+	Text  string
+	Style Style
+}
+
+// Strip removes all escape sequences from s.
+func Strip(s string) string {
+	if strings.IndexByte(s, esc) == -1 {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			n, _, _ := sequence(s[i:])
+			i += n
+			continue
+		}
+		buf.WriteByte(s[i])
+		i++
+	}
+	return buf.String()
+}
+
+// Offsets returns the offsets in s of the bytes which Strip keeps, so that
+// positions in the stripped text can be mapped back onto s.
+func Offsets(s string) []int {
+	offsets := make([]int, 0, len(s))
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			n, _, _ := sequence(s[i:])
+			i += n
+			continue
+		}
+		offsets = append(offsets, i)
+		i++
+	}
+	return offsets
+}
+
+// Parse splits s into segments of text, interpreting SGR (color) sequences
+// and dropping all other escape sequences.
+func Parse(s string) []Segment {
+	segments := []Segment{}
+	style := Style{}
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			segments = append(segments, Segment{buf.String(), style})
+			buf.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		if s[i] != esc {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+		n, final, params := sequence(s[i:])
+		i += n
+		if final == 'm' {
+			flush()
+			style = applySGR(style, params)
+		}
+	}
+	flush()
+	return segments
+}
+
+// sequence returns the length of the escape sequence at the start of s, along
+// with the final byte and parameters of CSI sequences.
+func sequence(s string) (n int, final byte, params string) {
+	if len(s) < 2 {
+		return len(s), 0, ""
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes followed by a final byte
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1, s[i], s[2:i]
+			}
+		}
+		return len(s), 0, ""
+	case ']':
+		// OSC: terminated by BEL or ST (ESC \)
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1, 0, ""
+			}
+			if s[i] == esc && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2, 0, ""
+			}
+		}
+		return len(s), 0, ""
+	default:
+		return 2, 0, ""
+	}
+}
+
+func applySGR(style Style, params string) Style {
+	codes := []int{}
+	for _, p := range strings.Split(params, ";") {
+		c, err := strconv.Atoi(p)
+		if err != nil {
+			c = 0
+		}
+		codes = append(codes, c)
+	}
+
+	for i := 0; i < len(codes); i++ {
+		switch c := codes[i]; {
+		case c == 0:
+			style = Style{}
+		case c == 1:
+			style.Bold = true
+		case c == 4:
+			style.Underline = true
+		case c == 22:
+			style.Bold = false
+		case c == 24:
+			style.Underline = false
+		case c >= 30 && c <= 37:
+			style.Fg = &Color{Index: c - 30}
+		case c >= 90 && c <= 97:
+			style.Fg = &Color{Index: c - 90 + 8}
+		case c == 39:
+			style.Fg = nil
+		case c >= 40 && c <= 47:
+			style.Bg = &Color{Index: c - 40}
+		case c >= 100 && c <= 107:
+			style.Bg = &Color{Index: c - 100 + 8}
+		case c == 49:
+			style.Bg = nil
+		case c == 38 || c == 48:
+			color, n := extendedColor(codes[i+1:])
+			i += n
+			if color == nil {
+				continue
+			}
+			if c == 38 {
+				style.Fg = color
+			} else {
+				style.Bg = color
+			}
+		}
+	}
+	return style
+}
+
+// extendedColor parses the arguments of a 38 or 48 SGR code, i.e. either
+// 5;n or 2;r;g;b, returning the number of codes consumed.
+func extendedColor(codes []int) (*Color, int) {
+	if len(codes) >= 2 && codes[0] == 5 {
+		return &Color{Index: codes[1] & 0xff}, 2
+	}
+	if len(codes) >= 4 && codes[0] == 2 {
+		return &Color{Index: -1, R: uint8(codes[1]), G: uint8(codes[2]), B: uint8(codes[3])}, 4
+	}
+	return nil, len(codes)
+}
+
+// RuneWidth returns the number of terminal cells the rune occupies.
+func RuneWidth(r rune) int {
+	if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+		return 0
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	if unicode.Is(wide, r) {
+		return 2
+	}
+	return 1
+}
+
+// wide holds the East Asian Wide (W) and Fullwidth (F) ranges, including the
+// emoji which terminals render using two cells.
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1},
+		{0x231a, 0x231b, 1},
+		{0x2329, 0x232a, 1},
+		{0x23e9, 0x23ec, 1},
+		{0x23f0, 0x23f0, 1},
+		{0x23f3, 0x23f3, 1},
+		{0x25fd, 0x25fe, 1},
+		{0x2614, 0x2615, 1},
+		{0x2648, 0x2653, 1},
+		{0x267f, 0x267f, 1},
+		{0x2693, 0x2693, 1},
+		{0x26a1, 0x26a1, 1},
+		{0x26aa, 0x26ab, 1},
+		{0x26bd, 0x26be, 1},
+		{0x26c4, 0x26c5, 1},
+		{0x26ce, 0x26ce, 1},
+		{0x26d4, 0x26d4, 1},
+		{0x26ea, 0x26ea, 1},
+		{0x26f2, 0x26f3, 1},
+		{0x26f5, 0x26f5, 1},
+		{0x26fa, 0x26fa, 1},
+		{0x26fd, 0x26fd, 1},
+		{0x2705, 0x2705, 1},
+		{0x270a, 0x270b, 1},
+		{0x2728, 0x2728, 1},
+		{0x274c, 0x274c, 1},
+		{0x274e, 0x274e, 1},
+		{0x2753, 0x2755, 1},
+		{0x2757, 0x2757, 1},
+		{0x2795, 0x2797, 1},
+		{0x27b0, 0x27b0, 1},
+		{0x27bf, 0x27bf, 1},
+		{0x2b1b, 0x2b1c, 1},
+		{0x2b50, 0x2b50, 1},
+		{0x2b55, 0x2b55, 1},
+		{0x2e80, 0x303e, 1},
+		{0x3041, 0x33ff, 1},
+		{0x3400, 0x4dbf, 1},
+		{0x4e00, 0x9fff, 1},
+		{0xa000, 0xa4cf, 1},
+		{0xa960, 0xa97f, 1},
+		{0xac00, 0xd7a3, 1},
+		{0xf900, 0xfaff, 1},
+		{0xfe10, 0xfe19, 1},
+		{0xfe30, 0xfe6f, 1},
+		{0xff00, 0xff60, 1},
+		{0xffe0, 0xffe6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x16fe0, 0x16fe4, 1},
+		{0x17000, 0x18aff, 1},
+		{0x1b000, 0x1b2ff, 1},
+		{0x1f004, 0x1f004, 1},
+		{0x1f0cf, 0x1f0cf, 1},
+		{0x1f18e, 0x1f18e, 1},
+		{0x1f191, 0x1f19a, 1},
+		{0x1f200, 0x1f251, 1},
+		{0x1f300, 0x1f64f, 1},
+		{0x1f680, 0x1f6ff, 1},
+		{0x1f900, 0x1f9ff, 1},
+		{0x20000, 0x2fffd, 1},
+		{0x30000, 0x3fffd, 1},
+	},
+}
+
+// Width returns the number of terminal cells s occupies, ignoring escape
+// sequences.
+func Width(s string) int {
+	w := 0
+	for _, r := range Strip(s) {
+		w += RuneWidth(r)
+	}
+	return w
+}
+
+// Truncate shortens s to a display width of at most n, ending it with tail if
+// anything was cut. Colors in effect at the cut are reset after tail.
+func Truncate(s string, n int, tail string) string {
+	if Width(s) <= n {
+		return s
+	}
+	head, _, active := cut(s, n-Width(tail), false)
+	if active != "" {
+		return head + tail + "\x1b[0m"
+	}
+	return head + tail
+}
+
+// Cut splits s after a display width of n. Any SGR sequences in effect at
+// the cut are reset at the end of head and repeated at the start of tail, so
+// that both halves can be printed on their own lines. At least one rune is
+// kept in head, even if it is wider than n.
+func Cut(s string, n int) (head, tail string) {
+	head, tail, active := cut(s, n, true)
+	if active != "" && tail != "" {
+		return head + "\x1b[0m", active + tail
+	}
+	return head, tail
+}
+
+// cut splits s after a display width of n, also returning the SGR sequences
+// in effect at the cut.
+func cut(s string, n int, keepOne bool) (head, tail, active string) {
+	w := 0
+	for i := 0; i < len(s); {
+		if s[i] == esc {
+			size, final, params := sequence(s[i:])
+			if final == 'm' {
+				if params == "" || params == "0" {
+					active = ""
+				} else {
+					active += s[i : i+size]
+				}
+			}
+			i += size
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := RuneWidth(r)
+		if w+rw > n && (w > 0 || !keepOne) {
+			return s[:i], s[i:], active
+		}
+		w += rw
+		i += size
+	}
+	return s, "", active
+}
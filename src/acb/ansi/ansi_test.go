@@ -0,0 +1,174 @@
+package ansi_test
+
+import (
+	"acb/ansi"
+	"reflect"
+	"testing"
+)
+
+// Ensure escape sequences are removed from text.
+func TestStrip(t *testing.T) {
+	var tests = []struct {
+		s   string
+		exp string
+	}{
+		{s: ``, exp: ``},
+		{s: `plain`, exp: `plain`},
+		{s: "\x1b[31mred\x1b[0m", exp: `red`},
+		{s: "\x1b[1;38;5;196mbold\x1b[22m text\x1b[m", exp: `bold text`},
+		{s: "a\x1b[2Kb\x1b[1Gc", exp: `abc`},
+		{s: "\x1b]0;title\x07x\x1b]8;;http://x\x1b\\y", exp: `xy`},
+		{s: "key=\x1b[32mvalue\x1b[39m", exp: `key=value`},
+		{s: "truncated\x1b[3", exp: `truncated`},
+	}
+
+	for i, tt := range tests {
+		if got := ansi.Strip(tt.s); got != tt.exp {
+			t.Errorf("%d. %q: exp=%q got=%q", i, tt.s, tt.exp, got)
+		}
+		got := ""
+		for _, o := range ansi.Offsets(tt.s) {
+			got += tt.s[o : o+1]
+		}
+		if got != tt.exp {
+			t.Errorf("%d. %q: offsets: exp=%q got=%q", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// Ensure SGR sequences are interpreted into styled segments.
+func TestParse(t *testing.T) {
+	segments := ansi.Parse("a\x1b[1;31mb\x1b[38;2;1;2;3;48;5;16mc\x1b[0md")
+	exp := []ansi.Segment{
+		{Text: "a"},
+		{Text: "b", Style: ansi.Style{Bold: true, Fg: &ansi.Color{Index: 1}}},
+		{Text: "c", Style: ansi.Style{Bold: true, Fg: &ansi.Color{Index: -1, R: 1, G: 2, B: 3}, Bg: &ansi.Color{Index: 16}}},
+		{Text: "d"},
+	}
+	if !reflect.DeepEqual(exp, segments) {
+		t.Errorf("segment mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", exp, segments)
+	}
+}
+
+func TestColor_RGB(t *testing.T) {
+	var tests = []struct {
+		c       ansi.Color
+		r, g, b uint8
+	}{
+		{c: ansi.Color{Index: 1}, r: 205},
+		{c: ansi.Color{Index: 196}, r: 255},
+		{c: ansi.Color{Index: 232}, r: 8, g: 8, b: 8},
+		{c: ansi.Color{Index: -1, R: 1, G: 2, B: 3}, r: 1, g: 2, b: 3},
+	}
+	for i, tt := range tests {
+		r, g, b := tt.c.RGB()
+		if r != tt.r || g != tt.g || b != tt.b {
+			t.Errorf("%d. exp=%d,%d,%d got=%d,%d,%d", i, tt.r, tt.g, tt.b, r, g, b)
+		}
+	}
+}
+
+// Ensure the display width ignores escapes and counts wide characters twice.
+func TestWidth(t *testing.T) {
+	var tests = []struct {
+		s   string
+		exp int
+	}{
+		{s: `abc`, exp: 3},
+		{s: "\x1b[31mabc\x1b[0m", exp: 3},
+		{s: `日本語`, exp: 6},
+		{s: `ｈｉ`, exp: 4},
+		{s: "é", exp: 1},
+		{s: `héllo`, exp: 5},
+	}
+	for i, tt := range tests {
+		if got := ansi.Width(tt.s); got != tt.exp {
+			t.Errorf("%d. %q: exp=%d got=%d", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// Ensure text is truncated to a display width, keeping escape sequences.
+func TestTruncate(t *testing.T) {
+	var tests = []struct {
+		s   string
+		n   int
+		exp string
+	}{
+		{s: `abc`, n: 3, exp: `abc`},
+		{s: `abcdef`, n: 4, exp: `abc…`},
+		{s: "\x1b[31mabcdef\x1b[0m", n: 4, exp: "\x1b[31mabc…\x1b[0m"},
+		{s: `日本語`, n: 4, exp: `日…`},
+		{s: `日本語`, n: 5, exp: `日本…`},
+	}
+	for i, tt := range tests {
+		if got := ansi.Truncate(tt.s, tt.n, "…"); got != tt.exp {
+			t.Errorf("%d. %q: exp=%q got=%q", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// Ensure styles are rendered using the colors available at each depth.
+func TestStyle_Render(t *testing.T) {
+	red := ansi.Style{Fg: ansi.RGBColor(255, 0, 0), Bg: &ansi.Color{Index: 236}, Bold: true}
+	var tests = []struct {
+		style ansi.Style
+		depth ansi.Depth
+		exp   string
+	}{
+		{style: red, depth: ansi.NoColor, exp: "x"},
+		{style: red, depth: ansi.Color16, exp: "\x1b[1;91;40mx\x1b[0m"},
+		{style: red, depth: ansi.Color256, exp: "\x1b[1;38;5;196;48;5;236mx\x1b[0m"},
+		{style: red, depth: ansi.TrueColor, exp: "\x1b[1;38;2;255;0;0;48;5;236mx\x1b[0m"},
+		{style: ansi.Style{Fg: ansi.RGBColor(128, 128, 128)}, depth: ansi.Color256, exp: "\x1b[38;5;244mx\x1b[0m"},
+		{style: ansi.Style{Fg: &ansi.Color{Index: 3}}, depth: ansi.Color16, exp: "\x1b[33mx\x1b[0m"},
+		{style: ansi.Style{Fg: ansi.RGBColor(0, 100, 90)}, depth: ansi.Color16, exp: "\x1b[96mx\x1b[0m"},
+		{style: ansi.Style{Fg: ansi.RGBColor(28, 28, 28)}, depth: ansi.Color16, exp: "\x1b[30mx\x1b[0m"},
+		{style: ansi.Style{}, depth: ansi.TrueColor, exp: "x"},
+	}
+	for i, tt := range tests {
+		if got := tt.style.Render("x", tt.depth); got != tt.exp {
+			t.Errorf("%d. exp=%q got=%q", i, tt.exp, got)
+		}
+	}
+}
+
+// Ensure styles are parsed from colors, attributes and backgrounds.
+func TestParseStyle(t *testing.T) {
+	style, err := ansi.ParseStyle("bold #ff0000 on 236")
+	if err != nil {
+		t.Fatal(err)
+	} else if !style.Bold || *style.Fg != *ansi.RGBColor(255, 0, 0) || style.Bg.Index != 236 {
+		t.Errorf("unexpected style %#v", style)
+	}
+	if style, _ := ansi.ParseStyle("bright-red"); style.Fg.Index != 9 {
+		t.Errorf("expected bright red, got %#v", style.Fg)
+	}
+
+	for _, s := range []string{"pink", "#12345", "256"} {
+		if _, err := ansi.ParseStyle(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+// Ensure text is cut at a display width, carrying its style over.
+func TestCut(t *testing.T) {
+	var tests = []struct {
+		s          string
+		n          int
+		head, tail string
+	}{
+		{s: `abc`, n: 5, head: `abc`, tail: ``},
+		{s: `abcdef`, n: 4, head: `abcd`, tail: `ef`},
+		{s: "\x1b[31mabcdef\x1b[0m", n: 2, head: "\x1b[31mab\x1b[0m", tail: "\x1b[31mcdef\x1b[0m"},
+		{s: "\x1b[31mab\x1b[0mcd", n: 3, head: "\x1b[31mab\x1b[0mc", tail: `d`},
+		{s: `日本`, n: 3, head: `日`, tail: `本`},
+		{s: `日本`, n: 1, head: `日`, tail: `本`},
+	}
+	for i, tt := range tests {
+		if head, tail := ansi.Cut(tt.s, tt.n); head != tt.head || tail != tt.tail {
+			t.Errorf("%d. %q: exp=%q,%q got=%q,%q", i, tt.s, tt.head, tt.tail, head, tail)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package ansi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Depth is the number of colors a terminal can display.
+type Depth int
+
+const (
+	// NoColor renders text without any escape sequences
+	NoColor Depth = iota
+	// Color16 uses the 16 basic colors
+	Color16
+	// Color256 uses the xterm 256 color palette
+	Color256
+	// TrueColor uses 24-bit colors
+	TrueColor
+)
+
+// RGBColor returns a 24-bit color.
+func RGBColor(r, g, b uint8) *Color {
+	return &Color{Index: -1, R: r, G: g, B: b}
+}
+
+// cubeIndex returns the index of the nearest level of the 6x6x6 color cube.
+func cubeIndex(v uint8) int {
+	switch {
+	case v < 48:
+		return 0
+	case v < 115:
+		return 1
+	default:
+		return (int(v) - 35) / 40
+	}
+}
+
+// to256 returns the nearest color of the xterm 256 color palette.
+func to256(r, g, b uint8) int {
+	if r == g && g == b {
+		switch {
+		case r < 8:
+			return 16
+		case r > 248:
+			return 231
+		case r >= 233:
+			return 255
+		default:
+			return 232 + (int(r)-3)/10
+		}
+	}
+	return 16 + 36*cubeIndex(r) + 6*cubeIndex(g) + cubeIndex(b)
+}
+
+// to16 returns the nearest of the 16 basic colors. Colors which are not gray
+// are matched at full brightness, so that e.g. dark teal becomes cyan rather
+// than black.
+func to16(r, g, b uint8) int {
+	max, min := r, r
+	for _, v := range []uint8{g, b} {
+		if v > max {
+			max = v
+		}
+		if v < min {
+			min = v
+		}
+	}
+	if max-min >= 40 {
+		r = uint8(int(r) * 255 / int(max))
+		g = uint8(int(g) * 255 / int(max))
+		b = uint8(int(b) * 255 / int(max))
+	}
+	best, bestDist := 0, -1
+	for i, c := range basic {
+		dr, dg, db := int(r)-int(c[0]), int(g)-int(c[1]), int(b)-int(c[2])
+		if dist := dr*dr + dg*dg + db*db; bestDist == -1 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// sgr returns the SGR parameters selecting the color as a foreground, or
+// background, color at the given depth.
+func (c Color) sgr(depth Depth, bg bool) string {
+	base := 38
+	if bg {
+		base = 48
+	}
+	switch {
+	case depth == TrueColor && c.Index < 0:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, c.R, c.G, c.B)
+	case depth >= Color256 && c.Index >= 0:
+		return fmt.Sprintf("%d;5;%d", base, c.Index)
+	case depth >= Color256:
+		return fmt.Sprintf("%d;5;%d", base, to256(c.R, c.G, c.B))
+	}
+
+	i := c.Index
+	if i < 0 || i >= 16 {
+		i = to16(c.RGB())
+	}
+	code := 30 + i
+	if i >= 8 {
+		code = 90 + i - 8
+	}
+	if bg {
+		code += 10
+	}
+	return strconv.Itoa(code)
+}
+
+// Render returns text in the style, reset at the end, using colors the
+// terminal can display. Nothing is added at NoColor depth.
+func (s Style) Render(text string, depth Depth) string {
+	if depth == NoColor || text == "" {
+		return text
+	}
+	codes := []string{}
+	if s.Bold {
+		codes = append(codes, "1")
+	}
+	if s.Underline {
+		codes = append(codes, "4")
+	}
+	if s.Fg != nil {
+		codes = append(codes, s.Fg.sgr(depth, false))
+	}
+	if s.Bg != nil {
+		codes = append(codes, s.Bg.sgr(depth, true))
+	}
+	if len(codes) == 0 {
+		return text
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ParseColor parses a color name such as red or bright-red, a palette index
+// from 0 to 255 or a #rrggbb value.
+func ParseColor(s string) (*Color, error) {
+	switch s {
+	case "gray", "grey":
+		return &Color{Index: 8}, nil
+	}
+	for i, name := range colorNames {
+		if s == name {
+			return &Color{Index: i}, nil
+		}
+		if s == "bright-"+name {
+			return &Color{Index: i + 8}, nil
+		}
+	}
+	if len(s) == 7 && s[0] == '#' {
+		if rgb, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return RGBColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < 256 {
+		return &Color{Index: i}, nil
+	}
+	return nil, fmt.Errorf("unknown color %q", s)
+}
+
+// ParseStyle parses a space separated style such as "bold red" or
+// "#ffffff on red"; the color following "on" is the background.
+func ParseStyle(s string) (Style, error) {
+	style := Style{}
+	bg := false
+	for _, word := range strings.Fields(s) {
+		switch word {
+		case "bold":
+			style.Bold = true
+		case "underline":
+			style.Underline = true
+		case "on":
+			bg = true
+		default:
+			c, err := ParseColor(word)
+			if err != nil {
+				return Style{}, err
+			}
+			if bg {
+				style.Bg = c
+			} else {
+				style.Fg = c
+			}
+		}
+	}
+	return style, nil
+}
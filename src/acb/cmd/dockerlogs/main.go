@@ -3,50 +3,229 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"acb"
+	"acb/ansi"
+
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 // curl --unix-socket /var/run/docker.sock 'http:/containers/1a210a4481b7/logs?stderr=1&stdout=1&timestamps=1&follow=1'
 
+var (
+	flags      = dockerlogs.NewFlags(kingpin.CommandLine)
+	formats    = kingpin.Flag("format", "Pin the log format of a container instead of detecting it, e.g. web=json or 'nginx*=clf'.").PlaceHolder("CONTAINER=FORMAT").StringMap()
+	merged     = kingpin.Flag("merged-context", "Take --grep context from all containers rather than the matching container.").Bool()
+	rateLimit  = kingpin.Flag("rate-limit", "Keep at most this many logs per second from each container, apart from errors.").PlaceHolder("N").Float64()
+	sample     = kingpin.Flag("sample", "Keep this fraction of the logs of each container at random, apart from errors, e.g. 0.1.").PlaceHolder("FRACTION").Float64()
+	timeOnly   = kingpin.Flag("time-only", "Only show the time of day of timestamps.").Bool()
+	nameWidth  = kingpin.Flag("name-width", "Truncate container names to this many columns, 0 for no limit.").Default("30").Int()
+	abbreviate = kingpin.Flag("abbreviate", "Drop the compose project from names such as project_web_1.").Bool()
+	containers = kingpin.Arg("container", "Only show the logs of these containers.").Strings()
+)
+
 func main() {
-	names := os.Args[1:]
+	args, err := dockerlogs.ProfileArgs(kingpin.CommandLine, os.Args[1:], dockerlogs.DefaultProfilesPath())
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+	kingpin.MustParse(kingpin.CommandLine.Parse(args))
+
+	if err := dockerlogs.LoadParseRules(dockerlogs.DefaultParseRulesPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load parse rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dockerlogs.LoadThemes(dockerlogs.DefaultThemesPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load themes: %v\n", err)
+		os.Exit(1)
+	}
+	t, ok := dockerlogs.Themes[*flags.Theme]
+	if !ok {
+		kingpin.Fatalf("unknown theme %q", *flags.Theme)
+	}
+	depth := dockerlogs.DetectColorDepth(*flags.Color, os.Stdout)
+	dockerlogs.SetColors(t, depth)
+	if depth == ansi.NoColor {
+		*flags.ANSI = string(dockerlogs.ANSIStrip)
+	}
+
+	formatOverrides := dockerlogs.FormatOverrides(*formats)
+	if err := formatOverrides.Validate(); err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+
+	level := dockerlogs.UNKNOWN
+	if *flags.MinLevel != "" {
+		var err error
+		if level, err = dockerlogs.ParseLogLevel(*flags.MinLevel); err != nil {
+			kingpin.Fatalf("%v", err)
+		}
+	}
+	var where *dockerlogs.Where
+	if *flags.Where != "" {
+		var err error
+		if where, err = dockerlogs.ParseWhere(*flags.Where); err != nil {
+			kingpin.Fatalf("--where: %v", err)
+		}
+	}
+
+	if *rateLimit < 0 {
+		kingpin.Fatalf("--rate-limit must not be negative")
+	}
+	if *sample < 0 || *sample > 1 {
+		kingpin.Fatalf("--sample must be between 0 and 1")
+	}
 
 	cli := dockerlogs.MustGetDockerCli()
-	lt := dockerlogs.NewLogTail(cli)
+	names := &dockerlogs.NameColumn{
+		MaxWidth:   *nameWidth,
+		Abbreviate: *abbreviate,
+	}
+	lt := dockerlogs.NewLogTail(cli, dockerlogs.LogTailOptions{
+		Names:        *containers,
+		Formats:      formatOverrides,
+		ANSI:         dockerlogs.ANSIMode(*flags.ANSI),
+		InferLevels:  *flags.InferLevels,
+		NameColumn:   names,
+		PollInterval: dockerlogs.DefaultPollInterval,
+		Limit:        dockerlogs.RateLimit{Rate: *rateLimit, Sample: *sample},
+	})
 
-	maxContainerNameLength := dockerlogs.GetMaxContainerNameLength(cli)
+	// times are shown in the zone docker reports timestamps in, unless
+	// --tz is given
+	var loc *time.Location
+	if *flags.TimeZone != "" {
+		var err error
+		if loc, err = dockerlogs.ParseTimeZone(*flags.TimeZone); err != nil {
+			kingpin.Fatalf("--tz: %v", err)
+		}
+	}
+	var unit time.Duration
+	if *flags.Precision != "" {
+		unit, _ = dockerlogs.ParsePrecision(*flags.Precision)
+	}
+	var timeRange *dockerlogs.TimeRange
+	if *flags.From != "" || *flags.To != "" {
+		rangeLoc := loc
+		if rangeLoc == nil {
+			rangeLoc = time.UTC
+		}
+		var err error
+		if timeRange, err = dockerlogs.ParseTimeRange(*flags.From, *flags.To, rangeLoc); err != nil {
+			kingpin.Fatalf("%v", err)
+		}
+	}
+	var trace *dockerlogs.Trace
+	if len(*flags.Trace) > 0 {
+		trace = dockerlogs.NewTrace(*flags.Trace...)
+		trace.Follow = *flags.TraceFollow
+	}
+	var grep *dockerlogs.Grep
+	if *flags.Grep != "" {
+		pattern, err := regexp.Compile(*flags.Grep)
+		if err != nil {
+			kingpin.Fatalf("--grep: %v", err)
+		}
+		grep = &dockerlogs.Grep{Pattern: pattern, Fields: flags.Projection(), Merged: *merged}
+		grep.Before, grep.After = flags.GrepContext()
+	}
+	formatter := &dockerlogs.Formatter{
+		Fields: flags.FieldOrder(),
+		Width:  dockerlogs.TerminalWidth(os.Stdout),
+		Wrap:   dockerlogs.WrapMode(*flags.Wrap),
+	}
+	if grep != nil {
+		formatter.Highlight = grep.Pattern
+	}
+	for i, spec := range *flags.Highlights {
+		rule, err := dockerlogs.ParseHighlightRule(spec, i)
+		if err != nil {
+			kingpin.Fatalf("--highlight: %v", err)
+		}
+		formatter.Highlights = append(formatter.Highlights, rule)
+	}
+	if *flags.Template != "" && *flags.Output != dockerlogs.OutputText {
+		kingpin.Fatalf("--template can only be used with --output text")
+	}
+	out, err := dockerlogs.NewRecordWriter(*flags.Output, os.Stdout, dockerlogs.OutputOptions{
+		Formatter:       formatter,
+		Names:           names,
+		TimestampLayout: dockerlogs.TimestampLayout(unit, *timeOnly),
+		Template:        *flags.Template,
+		Relative:        *flags.Relative,
+		Location:        loc,
+		Precision:       unit,
+		Fields:          flags.Projection(),
+	})
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
 
 	// Sleep to make sure all files have been read by the corresponding thread
 	time.Sleep(10 * time.Millisecond)
 
-	for {
-		containerName, line := lt.GetLine()
-
-		// skip container if not listed
-		if len(names) > 0 {
-			found := false
-			for _, xx := range names {
-				if containerName == xx {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
+	write := func(records []*dockerlogs.Record) {
+		for _, r := range records {
+			if err := out.Write(r); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write output: %v\n", err)
+				os.Exit(1)
 			}
 		}
+	}
+	var collapser *dockerlogs.Collapser
+	if *flags.Collapse {
+		collapser = &dockerlogs.Collapser{Timeout: *flags.CollapseFor}
+	}
 
-		timestamp := line.Timestamp.Format("2006-01-02T15:04:05")
+	for {
+		var timeout <-chan time.Time
+		if collapser != nil {
+			write(collapser.Flush(time.Now(), false))
+			if due, ok := collapser.Due(); ok {
+				timeout = time.After(due.Sub(time.Now()))
+			}
+		}
+		src, line, ok := lt.GetLineUntil(timeout)
+		if !ok {
+			continue
+		}
 
 		if line.Line != "" {
-			parsedLog := dockerlogs.ParseLog(line.Line)
-
-			fmt.Printf("%s %s %s\n",
-				dockerlogs.PadLeft(containerName, maxContainerNameLength),
-				timestamp,
-				parsedLog.Format())
+			if line.Log.Level < level {
+				continue
+			}
+			record := &dockerlogs.Record{
+				Source:    src,
+				Timestamp: line.Timestamp,
+				Stream:    line.Stream,
+				Line:      line.Line,
+				Log:       line.Log,
+			}
+			if where != nil && !where.Match(record) {
+				continue
+			}
+			if timeRange != nil && !timeRange.Match(record) {
+				continue
+			}
+			if trace != nil && !trace.Add(record) {
+				continue
+			}
+			records := []*dockerlogs.Record{record}
+			if grep != nil {
+				var gap bool
+				if records, gap = grep.Filter(record); gap && *flags.Output == dockerlogs.OutputText {
+					fmt.Println("--")
+				}
+			}
+			for _, r := range records {
+				if collapser != nil {
+					write(collapser.Add(r, time.Now()))
+				} else {
+					write([]*dockerlogs.Record{r})
+				}
+			}
 		}
 	}
 
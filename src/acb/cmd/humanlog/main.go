@@ -2,30 +2,221 @@ package main
 
 import (
 	"acb"
+	"acb/ansi"
 	"bufio"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 // curl --unix-socket /var/run/docker.sock 'http:/containers/1a210a4481b7/logs?stderr=1&stdout=1&timestamps=1&follow=1'
 
+var (
+	flags  = dockerlogs.NewFlags(kingpin.CommandLine)
+	format = kingpin.Flag("format", "Parse every line using this format instead of detecting it, e.g. json, logfmt or clf.").String()
+)
+
 func main() {
+	args, err := dockerlogs.ProfileArgs(kingpin.CommandLine, os.Args[1:], dockerlogs.DefaultProfilesPath())
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+	kingpin.MustParse(kingpin.CommandLine.Parse(args))
 
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		text, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
+	if err := dockerlogs.LoadParseRules(dockerlogs.DefaultParseRulesPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load parse rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dockerlogs.LoadThemes(dockerlogs.DefaultThemesPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load themes: %v\n", err)
+		os.Exit(1)
+	}
+	t, ok := dockerlogs.Themes[*flags.Theme]
+	if !ok {
+		kingpin.Fatalf("unknown theme %q", *flags.Theme)
+	}
+	depth := dockerlogs.DetectColorDepth(*flags.Color, os.Stdout)
+	dockerlogs.SetColors(t, depth)
+	if depth == ansi.NoColor {
+		*flags.ANSI = string(dockerlogs.ANSIStrip)
+	}
+
+	parser, err := dockerlogs.NewSourceParser(dockerlogs.Source{}, *format)
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+	parser.ANSI = dockerlogs.ANSIMode(*flags.ANSI)
+	parser.InferLevels = *flags.InferLevels
+
+	// times are shown in the zone docker reports timestamps in, unless
+	// --tz is given
+	var loc *time.Location
+	if *flags.TimeZone != "" {
+		var err error
+		if loc, err = dockerlogs.ParseTimeZone(*flags.TimeZone); err != nil {
+			kingpin.Fatalf("--tz: %v", err)
 		}
+	}
+	var unit time.Duration
+	if *flags.Precision != "" {
+		unit, _ = dockerlogs.ParsePrecision(*flags.Precision)
+	}
+	var timeRange *dockerlogs.TimeRange
+	if *flags.From != "" || *flags.To != "" {
+		rangeLoc := loc
+		if rangeLoc == nil {
+			rangeLoc = time.UTC
+		}
+		var err error
+		if timeRange, err = dockerlogs.ParseTimeRange(*flags.From, *flags.To, rangeLoc); err != nil {
+			kingpin.Fatalf("%v", err)
+		}
+	}
+	var trace *dockerlogs.Trace
+	if len(*flags.Trace) > 0 {
+		trace = dockerlogs.NewTrace(*flags.Trace...)
+		trace.Follow = *flags.TraceFollow
+	}
+	var grep *dockerlogs.Grep
+	if *flags.Grep != "" {
+		pattern, err := regexp.Compile(*flags.Grep)
 		if err != nil {
-			panic(err)
+			kingpin.Fatalf("--grep: %v", err)
 		}
-		text = strings.TrimSuffix(text, "\n")
-		parsedLog := dockerlogs.ParseLog(text)
+		grep = &dockerlogs.Grep{Pattern: pattern, Fields: flags.Projection()}
+		grep.Before, grep.After = flags.GrepContext()
+	}
+	formatter := &dockerlogs.Formatter{
+		Fields: flags.FieldOrder(),
+		Width:  dockerlogs.TerminalWidth(os.Stdout),
+		Wrap:   dockerlogs.WrapMode(*flags.Wrap),
+	}
+	if grep != nil {
+		formatter.Highlight = grep.Pattern
+	}
+	for i, spec := range *flags.Highlights {
+		rule, err := dockerlogs.ParseHighlightRule(spec, i)
+		if err != nil {
+			kingpin.Fatalf("--highlight: %v", err)
+		}
+		formatter.Highlights = append(formatter.Highlights, rule)
+	}
+	if *flags.Template != "" && *flags.Output != dockerlogs.OutputText {
+		kingpin.Fatalf("--template can only be used with --output text")
+	}
+	out, err := dockerlogs.NewRecordWriter(*flags.Output, os.Stdout, dockerlogs.OutputOptions{
+		Formatter: formatter,
+		Template:  *flags.Template,
+		Relative:  *flags.Relative,
+		Location:  loc,
+		Precision: unit,
+		Fields:    flags.Projection(),
+	})
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+
+	level := dockerlogs.UNKNOWN
+	if *flags.MinLevel != "" {
+		var err error
+		if level, err = dockerlogs.ParseLogLevel(*flags.MinLevel); err != nil {
+			kingpin.Fatalf("%v", err)
+		}
+	}
+	var where *dockerlogs.Where
+	if *flags.Where != "" {
+		var err error
+		if where, err = dockerlogs.ParseWhere(*flags.Where); err != nil {
+			kingpin.Fatalf("--where: %v", err)
+		}
+	}
 
-		fmt.Printf("%s\n", parsedLog.Format())
+	write := func(records []*dockerlogs.Record) {
+		for _, r := range records {
+			if err := out.Write(r); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write output: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	var collapser *dockerlogs.Collapser
+	if *flags.Collapse {
+		collapser = &dockerlogs.Collapser{Timeout: *flags.CollapseFor}
 	}
 
+	// lines are read in the background, so that collapsed repeats can be
+	// shown while stdin is quiet
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			text, err := reader.ReadString('\n')
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+			lines <- strings.TrimSuffix(text, "\n")
+		}
+	}()
+
+	for {
+		var timeout <-chan time.Time
+		if collapser != nil {
+			write(collapser.Flush(time.Now(), false))
+			if due, ok := collapser.Due(); ok {
+				timeout = time.After(due.Sub(time.Now()))
+			}
+		}
+		var text string
+		select {
+		case <-timeout:
+			continue
+		case line, ok := <-lines:
+			if !ok {
+				if collapser != nil {
+					write(collapser.Flush(time.Now(), true))
+				}
+				return
+			}
+			text = line
+		}
+
+		parsedLog := parser.Parse(text)
+		if parsedLog.Level < level {
+			continue
+		}
+
+		record := &dockerlogs.Record{Line: text, Log: parsedLog}
+		if where != nil && !where.Match(record) {
+			continue
+		}
+		if timeRange != nil && !timeRange.Match(record) {
+			continue
+		}
+		if trace != nil && !trace.Add(record) {
+			continue
+		}
+		records := []*dockerlogs.Record{record}
+		if grep != nil {
+			var gap bool
+			if records, gap = grep.Filter(record); gap && *flags.Output == dockerlogs.OutputText {
+				fmt.Println("--")
+			}
+		}
+		for _, r := range records {
+			if collapser != nil {
+				write(collapser.Add(r, time.Now()))
+			} else {
+				write([]*dockerlogs.Record{r})
+			}
+		}
+	}
 }
@@ -0,0 +1,114 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultCollapseTimeout is how long repeated logs are held before their
+// summary is shown.
+const DefaultCollapseTimeout = 5 * time.Second
+
+// variablePattern matches numbers and ids, such as hex strings and uuids,
+// which are masked when comparing messages.
+var variablePattern = regexp.MustCompile(`\b[0-9a-fA-F-]*[0-9][0-9a-fA-F-]*\b|[0-9]+`)
+
+// normaliseMsg masks the numbers and ids of a message, so that messages
+// which only differ in them compare equal.
+func normaliseMsg(msg string) string {
+	return variablePattern.ReplaceAllString(ansi.Strip(msg), "#")
+}
+
+// collapseGroup holds the repeats of the last log of a container.
+type collapseGroup struct {
+	key     string
+	summary *Record
+	due     time.Time
+}
+
+// Collapser folds consecutive logs of a container with the same level,
+// message and fields, ignoring numbers and ids, into one. The first log is shown as it
+// arrives; its repeats are held and shown as a single record, with Repeats
+// set, once a different log arrives or Timeout has passed.
+type Collapser struct {
+	Timeout time.Duration
+
+	groups map[string]*collapseGroup
+}
+
+// collapseKey returns what repeats of r share: its level, message and
+// fields, with numbers and ids masked, or its line if it has neither message
+// nor fields.
+func collapseKey(r *Record) string {
+	key := strconv.Itoa(int(r.Log.Level)) + " " + normaliseMsg(r.Log.Msg)
+	for _, kv := range r.Log.Context {
+		key += " " + kv.Key + "=" + normaliseMsg(kv.Value.String())
+	}
+	if r.Log.Msg == "" && len(r.Log.Context) == 0 {
+		key += normaliseMsg(r.Line)
+	}
+	return key
+}
+
+// Add returns the records to show now that r has been received.
+func (c *Collapser) Add(r *Record, now time.Time) []*Record {
+	if c.groups == nil {
+		c.groups = map[string]*collapseGroup{}
+	}
+	key := collapseKey(r)
+	g, ok := c.groups[r.Source.Name]
+	if !ok || g.key != key {
+		records := []*Record{}
+		if ok && g.summary != nil {
+			records = append(records, g.summary)
+		}
+		c.groups[r.Source.Name] = &collapseGroup{key: key}
+		return append(records, r)
+	}
+
+	repeats, first := 0, r.Timestamp
+	if g.summary == nil {
+		g.due = now.Add(c.Timeout)
+	} else {
+		repeats, first = g.summary.Repeats, g.summary.Timestamp
+	}
+	summary := *r
+	summary.Timestamp = first
+	summary.Repeats = repeats + 1
+	summary.LastTimestamp = r.Timestamp
+	g.summary = &summary
+	return nil
+}
+
+// Flush returns the summaries which are due at now, or all of them if all is
+// set, oldest first. Repeats which arrive after a summary is flushed are
+// collapsed into a new one.
+func (c *Collapser) Flush(now time.Time, all bool) []*Record {
+	records := []*Record{}
+	for _, g := range c.groups {
+		if g.summary != nil && (all || !now.Before(g.due)) {
+			records = append(records, g.summary)
+			g.summary = nil
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Timestamp.Equal(records[j].Timestamp) {
+			return records[i].Timestamp.Before(records[j].Timestamp)
+		}
+		return records[i].Source.Name < records[j].Source.Name
+	})
+	return records
+}
+
+// Due returns the time the next summary is due, if any are held.
+func (c *Collapser) Due() (due time.Time, ok bool) {
+	for _, g := range c.groups {
+		if g.summary != nil && (!ok || g.due.Before(due)) {
+			due, ok = g.due, true
+		}
+	}
+	return due, ok
+}
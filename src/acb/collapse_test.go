@@ -0,0 +1,95 @@
+package dockerlogs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormaliseMsg(t *testing.T) {
+	for _, tc := range []struct {
+		msg, exp string
+	}{
+		{"connection refused", "connection refused"},
+		{"dial tcp 10.0.0.1:5432: connection refused", "dial tcp #.#.#.#:#: connection refused"},
+		{"request 3f2b9c1e-0d4a-4c4e-9a53-2b1c8f0e7d61 took 12ms", "request # took #ms"},
+		{"user42 retry 3", "user# retry #"},
+		{"\x1b[31mfailed\x1b[0m after 3 attempts", "failed after # attempts"},
+	} {
+		if got := normaliseMsg(tc.msg); got != tc.exp {
+			t.Errorf("%q: exp=%q got=%q", tc.msg, tc.exp, got)
+		}
+	}
+}
+
+// Ensure logs only repeat if their fields match too, numbers and ids apart.
+func TestCollapseKey(t *testing.T) {
+	key := func(line string) string {
+		return collapseKey(&Record{Line: line, Log: ParseLog(line)})
+	}
+	for _, tt := range []struct {
+		a, b string
+		exp  bool
+	}{
+		{a: `{"event":"login"}`, b: `{"event":"logout"}`, exp: false},
+		{a: `{"event":"login","user":7}`, b: `{"event":"login","user":8}`, exp: true},
+		{a: `{"msg":"paid","item":"book"}`, b: `{"msg":"paid","item":"pen"}`, exp: false},
+		{a: `{"msg":"paid","id":"4bf92f35"}`, b: `{"msg":"paid","id":"00f067aa"}`, exp: true},
+		{a: `{"msg":"paid"}`, b: `{"msg":"paid","item":"pen"}`, exp: false},
+		{a: `{}`, b: `{}`, exp: true},
+	} {
+		if got := key(tt.a) == key(tt.b); got != tt.exp {
+			t.Errorf("%s and %s: exp=%v got=%v", tt.a, tt.b, tt.exp, got)
+		}
+	}
+}
+
+// Ensure repeats are held until the log changes or the timeout passes, per
+// container.
+func TestCollapser(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	c := &Collapser{Timeout: 5 * time.Second}
+	show := func(records []*Record) []string {
+		out := []string{}
+		for _, r := range records {
+			s := r.Source.Name + " " + r.Log.Msg
+			if r.Repeats > 0 {
+				s += fmt.Sprintf(" ×%d %s-%s", r.Repeats, r.Timestamp.Format("05"), r.LastTimestamp.Format("05"))
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	add := func(sec int, name, msg string) []string {
+		r := &Record{
+			Source:    Source{Name: name},
+			Timestamp: start.Add(time.Duration(sec) * time.Second),
+			Log:       &Log{Level: ERROR, Msg: msg},
+		}
+		return show(c.Add(r, r.Timestamp))
+	}
+
+	for _, step := range []struct {
+		got, exp []string
+	}{
+		{add(0, "web", "refused 1"), []string{"web refused 1"}},
+		{add(1, "web", "refused 2"), []string{}},
+		{add(1, "db", "ready"), []string{"db ready"}},
+		{add(2, "web", "refused 3"), []string{}},
+		{add(3, "web", "started"), []string{"web refused 3 ×2 01-02", "web started"}},
+		{add(4, "web", "started"), []string{}},
+		{show(c.Flush(start.Add(8*time.Second), false)), []string{}},
+		{show(c.Flush(start.Add(9*time.Second), false)), []string{"web started ×1 04-04"}},
+		{add(10, "web", "started"), []string{}},
+		{add(10, "db", "ready"), []string{}},
+		{show(c.Flush(start.Add(11*time.Second), true)), []string{"db ready ×1 10-10", "web started ×1 10-10"}},
+	} {
+		if !reflect.DeepEqual(step.got, step.exp) {
+			t.Errorf("exp=%q got=%q", step.exp, step.got)
+		}
+	}
+	if _, ok := c.Due(); ok {
+		t.Errorf("expected nothing to be due after flushing")
+	}
+}
@@ -1,7 +1,6 @@
 package dockerlogs
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -16,21 +15,61 @@ import (
 
 type logLine struct {
 	Timestamp time.Time
+	Stream    string
 	Line      string
+	Log       *Log
 }
 
 type containerLogs struct {
-	ID   string
-	Name string
-	line *logLine
-	ch   chan logLine
+	Source
+	ID     string
+	parser *SourceParser
+	line   *logLine
+	ch     chan logLine
+	// last is the timestamp of the last line received
+	last time.Time
 }
 
 type logtail struct {
+	cli               *client.Client
+	opts              LogTailOptions
 	containerLogsList []containerLogs
+	// ended holds the timestamp of the last line of a container, by ID, so
+	// that only its new logs are tailed if it is restarted
+	ended   map[string]time.Time
+	listed  chan []types.Container
+	pending []types.Container
 }
 
-func NewLogTail(cli *client.Client) *logtail {
+// LogTailOptions configures how container logs are tailed.
+type LogTailOptions struct {
+	// Names limits the containers tailed to those with these names.
+	Names []string
+
+	// Formats pins the format of matching containers, skipping detection.
+	Formats FormatOverrides
+
+	// ANSI controls how escape sequences in the container output are handled.
+	ANSI ANSIMode
+
+	// InferLevels guesses the level of lines which do not declare one.
+	InferLevels bool
+
+	// NameColumn, if set, is kept up to date with the containers tailed.
+	NameColumn *NameColumn
+
+	// PollInterval is how often to look for new containers, 0 disables it.
+	PollInterval time.Duration
+
+	// Limit drops logs of containers which log too much, reporting how many
+	// were dropped every DefaultNoticeInterval.
+	Limit RateLimit
+}
+
+// DefaultPollInterval is how often dockerlogs looks for new containers
+const DefaultPollInterval = 2 * time.Second
+
+func NewLogTail(cli *client.Client, opts LogTailOptions) *logtail {
 
 	options := types.ContainerListOptions{All: true}
 	containers, err := cli.ContainerList(context.Background(), options)
@@ -38,63 +77,187 @@ func NewLogTail(cli *client.Client) *logtail {
 		panic(err)
 	}
 
-	containerLogsList := []containerLogs{}
-
+	s := &logtail{
+		cli:    cli,
+		opts:   opts,
+		ended:  map[string]time.Time{},
+		listed: make(chan []types.Container, 1),
+	}
 	for _, c := range containers {
-		ch := make(chan logLine, 1000)
-		containerLogsList = append(containerLogsList, containerLogs{
-			ID:   c.ID,
-			Name: strings.TrimPrefix(c.Names[0], "/"),
-			line: nil,
-			ch:   ch,
-		})
-		fmt.Println(c.ID)
-		go tailDockerLog(c.ID, ch)
+		s.join(c)
+	}
+	if opts.PollInterval > 0 {
+		go s.poll()
+	}
+	return s
+}
+
+// poll lists the running containers every PollInterval, so that containers
+// which are started later are tailed too.
+func (s *logtail) poll() {
+	for {
+		time.Sleep(s.opts.PollInterval)
+		containers, err := s.cli.ContainerList(context.Background(), types.ContainerListOptions{})
+		if err != nil {
+			log.Printf("Failed to list containers: %v", err)
+			continue
+		}
+		s.listed <- containers
 	}
+}
+
+func (s *logtail) tailed(id string) bool {
+	for _, c := range s.containerLogsList {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
 
-	return &logtail{
-		containerLogsList,
+// join starts tailing a container, unless it is already tailed or not one of
+// the Names.
+func (s *logtail) join(c types.Container) {
+	src := NewSource(strings.TrimPrefix(c.Names[0], "/"), c.Image, c.Labels)
+	if len(s.opts.Names) > 0 && indexOf(s.opts.Names, src.Name) == -1 {
+		return
+	}
+	if s.tailed(c.ID) {
+		return
+	}
+
+	parser, err := NewSourceParser(src, s.opts.Formats.Lookup(src))
+	if err != nil {
+		panic(err)
 	}
+	if s.opts.ANSI != "" {
+		parser.ANSI = s.opts.ANSI
+	}
+	parser.InferLevels = s.opts.InferLevels
+
+	since := ""
+	if t, ok := s.ended[c.ID]; ok {
+		since = sinceArg(t)
+	}
+
+	ch := make(chan logLine, 1000)
+	s.containerLogsList = append(s.containerLogsList, containerLogs{
+		Source: src,
+		ID:     c.ID,
+		parser: parser,
+		line:   nil,
+		ch:     ch,
+	})
+	if s.opts.NameColumn != nil {
+		s.opts.NameColumn.Join(src)
+	}
+	var limit *limiter
+	if !s.opts.Limit.IsZero() {
+		limit = newLimiter(s.opts.Limit)
+	}
+	go tailDockerLog(c.ID, since, parser, limit, ch)
+}
+
+// sinceArg formats the since argument of the docker logs API, as a unix time
+// with nanoseconds, so that lines up to and including t are skipped.
+func sinceArg(t time.Time) string {
+	t = t.Add(time.Nanosecond)
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
 }
 
-func (s *logtail) readFromChannels() {
+// leave stops tailing the i'th container once its logs have ended.
+func (s *logtail) leave(i int) {
+	c := s.containerLogsList[i]
+	if c.last.IsZero() {
+		c.last = time.Now()
+	}
+	s.ended[c.ID] = c.last
+	s.containerLogsList = append(s.containerLogsList[:i], s.containerLogsList[i+1:]...)
+	if s.opts.NameColumn != nil {
+		s.opts.NameColumn.Leave(c.Source)
+	}
+}
+
+// readFromChannels fills the line of each container which has logs
+// available. If there are none it blocks until there are, new containers
+// are found, or timeout fires, in which case it returns false.
+func (s *logtail) readFromChannels(timeout <-chan time.Time) bool {
+	// start tailing containers found by poll
+	select {
+	case containers := <-s.listed:
+		s.pending = containers
+	default:
+	}
+	for _, c := range s.pending {
+		s.join(c)
+	}
+	s.pending = nil
+
 	// grab latest log from each channel (if available)
 	numEmptyChannels := 0
-	for i, _ := range s.containerLogsList {
+	for i := len(s.containerLogsList) - 1; i >= 0; i-- {
 		c := &s.containerLogsList[i]
 		if c.line == nil {
 			select {
-			case x := <-c.ch:
-				c.line = &x
+			case x, ok := <-c.ch:
+				if !ok {
+					s.leave(i)
+					continue
+				}
+				c.line, c.last = &x, x.Timestamp
 			default:
 				numEmptyChannels++
 			}
 		}
 	}
 
-	// if no logs are available, block until at least one is
+	// if no logs are available, block until at least one is, new
+	// containers are found or the timeout fires
 	if numEmptyChannels == len(s.containerLogsList) {
-		cases := make([]reflect.SelectCase, len(s.containerLogsList))
+		n := len(s.containerLogsList)
+		cases := make([]reflect.SelectCase, n+1, n+2)
 		for i, _ := range s.containerLogsList {
 			ch := s.containerLogsList[i].ch
 			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
 		}
+		cases[n] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.listed)}
+		if timeout != nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timeout)})
+		}
 
 		// Block
 		chosen, value, ok := reflect.Select(cases)
 
-		if !ok {
-			panic("not ok channel from select")
+		switch {
+		case chosen == n+1:
+			return false
+		case chosen == n:
+			s.pending = value.Interface().([]types.Container)
+		case !ok:
+			s.leave(chosen)
+		default:
+			logLine := value.Interface().(logLine)
+			c := &s.containerLogsList[chosen]
+			c.line, c.last = &logLine, logLine.Timestamp
 		}
-
-		logLine := value.Interface().(logLine)
-		s.containerLogsList[chosen].line = &logLine
 	}
+	return true
 }
 
-func (s *logtail) GetLine() (string, *logLine) {
+// GetLine returns the oldest line available from the tailed containers,
+// blocking until there is one.
+func (s *logtail) GetLine() (Source, *logLine) {
+	src, line, _ := s.GetLineUntil(nil)
+	return src, line
+}
+
+// GetLineUntil is like GetLine, but gives up and returns false when timeout
+// fires first.
+func (s *logtail) GetLineUntil(timeout <-chan time.Time) (Source, *logLine, bool) {
 	for {
-		s.readFromChannels()
+		if !s.readFromChannels(timeout) {
+			return Source{}, nil, false
+		}
 
 		mini := -1
 		for i, _ := range s.containerLogsList {
@@ -114,62 +277,108 @@ func (s *logtail) GetLine() (string, *logLine) {
 			c := &s.containerLogsList[mini]
 			line := c.line
 			c.line = nil
-			return c.Name, line
+			return c.Source, line, true
 		}
 	}
 }
 
-func tailDockerLog(containerID string, ch chan<- logLine) {
+// tailDockerLog sends the logs of a container since the given unix time, or
+// all of them, to ch. Logs are dropped before they are sent if limit is set,
+// and the number dropped is reported every DefaultNoticeInterval, even if the
+// container logs nothing else. The channel is closed when the logs end.
+func tailDockerLog(containerID, since string, parser *SourceParser, limit *limiter, ch chan<- logLine) {
+	defer close(ch)
 	cli := MustGetDockerCli()
 
-	body, _ := cli.ContainerLogs(context.Background(), containerID, types.ContainerLogsOptions{
+	body, err := cli.ContainerLogs(context.Background(), containerID, types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
+		Since:      since,
 		Timestamps: true,
 		Follow:     true,
 	})
+	if err != nil {
+		log.Printf("Failed to tail container %v: %v", containerID, err)
+		return
+	}
+	defer body.Close()
 
-	reader := bufio.NewReader(body)
+	lines := make(chan logLine)
+	go readDockerLog(containerID, newLogReader(body), parser, lines)
+	if limit == nil {
+		for line := range lines {
+			ch <- line
+		}
+		return
+	}
+
+	ticker := time.NewTicker(DefaultNoticeInterval)
+	defer ticker.Stop()
+	limitLogs(lines, limit, ticker.C, ch)
+}
 
+// limitLogs sends the lines kept by limit to ch, with the number of lines
+// dropped when it is due and at each tick, until lines is closed.
+func limitLogs(lines <-chan logLine, limit *limiter, tick <-chan time.Time, ch chan<- logLine) {
 	for {
-		line, err := reader.ReadString('\n')
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if n := limit.notice(limit.last, true); n > 0 {
+					ch <- droppedLine(n, limit.last)
+				}
+				return
+			}
+			keep := limit.allow(line.Log, line.Timestamp)
+			if n := limit.notice(line.Timestamp, false); n > 0 {
+				ch <- droppedLine(n, line.Timestamp)
+			}
+			if keep {
+				ch <- line
+			}
+		case <-tick:
+			if n := limit.notice(limit.last, true); n > 0 {
+				ch <- droppedLine(n, limit.last)
+			}
+		}
+	}
+}
+
+// readDockerLog parses the lines of a container's log into lines, which is
+// closed when the log ends.
+func readDockerLog(containerID string, reader *logReader, parser *SourceParser, lines chan<- logLine) {
+	defer close(lines)
+	for {
+		stream, line, err := reader.ReadLine()
 		if err == io.EOF {
-			fmt.Printf("exiting\n")
 			return
 		} else if err != nil {
 			log.Fatalf("Failed to read container %v log: %v", containerID, err)
 		}
-		if line[len(line)-1] == '\n' {
-			line = line[:len(line)-1]
-		}
-
-		//TODO figure out whats in the first 8 bytes
-		//double TODO, sometimes a line is truncated, then the next line contains less than 8 bytes before the timestamp
-		//so we will just remove all bytes until we find a '2' as in year '2004', this is terrible.
-		for {
-			if len(line) < 5 {
-				break
-			}
-
-			if line[0] == '2' && line[4] == '-' {
-				break
-			}
-			line = line[1:]
-		}
 		if len(line) < 10 {
 			continue
 		}
 
 		x := strings.SplitN(line, " ", 2)
+		if len(x) < 2 {
+			x = append(x, "")
+		}
 
 		timestamp, err := time.Parse(time.RFC3339Nano, x[0])
 		if err != nil {
 			log.Fatalf("Failed to parse timestamp %s: %s\n", x[0], err)
 		}
 
-		ch <- logLine{
+		text := strings.Trim(x[1], " \n\t\r")
+		var parsedLog *Log
+		if text != "" {
+			parsedLog = parser.Parse(text)
+		}
+		lines <- logLine{
 			Timestamp: timestamp,
-			Line:      strings.Trim(x[1], " \n\t\r"),
+			Stream:    stream,
+			Line:      text,
+			Log:       parsedLog,
 		}
 	}
 }
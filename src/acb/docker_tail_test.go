@@ -0,0 +1,14 @@
+package dockerlogs
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure restarted containers are tailed from just after their last line.
+func TestSinceArg(t *testing.T) {
+	last := time.Date(2017, 1, 1, 10, 0, 0, 123456789, time.UTC)
+	if got, exp := sinceArg(last), "1483264800.123456790"; got != exp {
+		t.Errorf("exp=%s got=%s", exp, got)
+	}
+}
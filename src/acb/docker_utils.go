@@ -1,11 +1,7 @@
 package dockerlogs
 
 import (
-	"strings"
-
 	"github.com/docker/engine-api/client"
-	"github.com/docker/engine-api/types"
-	"golang.org/x/net/context"
 )
 
 func MustGetDockerCli() *client.Client {
@@ -16,20 +12,3 @@ func MustGetDockerCli() *client.Client {
 	}
 	return cli
 }
-
-func GetMaxContainerNameLength(cli *client.Client) int {
-	options := types.ContainerListOptions{All: true}
-	containers, err := cli.ContainerList(context.Background(), options)
-	if err != nil {
-		panic(err)
-	}
-
-	l := 0
-	for _, c := range containers {
-		n := strings.TrimPrefix(c.Names[0], "/")
-		if len(n) > l {
-			l = len(n)
-		}
-	}
-	return l
-}
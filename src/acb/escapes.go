@@ -0,0 +1,64 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"strings"
+)
+
+// ANSIMode controls what happens to escape sequences which containers embed
+// in their output. Lines are always parsed with the sequences stripped, the
+// mode only applies to the message of unstructured (raw) lines.
+type ANSIMode string
+
+const (
+	// ANSIStrip removes escape sequences
+	ANSIStrip ANSIMode = "strip"
+	// ANSIKeep passes escape sequences through unchanged
+	ANSIKeep ANSIMode = "keep"
+	// ANSITheme replaces the colors of escape sequences with our own
+	ANSITheme ANSIMode = "theme"
+)
+
+// ANSIModes lists the valid values of --ansi
+var ANSIModes = []string{string(ANSIStrip), string(ANSIKeep), string(ANSITheme)}
+
+// ansiPalette maps the 8 basic terminal colors onto the colors the dark theme
+// uses for levels, so that e.g. red output from npm matches our errors.
+var ansiPalette = [8]*ansi.Color{
+	ansi.RGBColor(120, 120, 120), // black
+	ansi.RGBColor(255, 0, 0),     // red
+	ansi.RGBColor(20, 190, 60),   // green
+	ansi.RGBColor(255, 245, 32),  // yellow
+	ansi.RGBColor(20, 172, 190),  // blue
+	ansi.RGBColor(221, 28, 119),  // magenta
+	ansi.RGBColor(20, 172, 190),  // cyan
+	ansi.RGBColor(255, 255, 255), // white
+}
+
+// colorDepth is the number of colors used to render logs, see SetColors.
+var colorDepth = ansi.TrueColor
+
+func paint(style ansi.Style, s string) string {
+	return style.Render(s, colorDepth)
+}
+
+// paletteColor replaces the basic colors with those of the theme.
+func paletteColor(c *ansi.Color) *ansi.Color {
+	if c != nil && c.Index >= 0 && c.Index < 16 {
+		return theme.ANSI[c.Index%8]
+	}
+	return c
+}
+
+// translateANSI re-renders the styled segments of s using the colors of the
+// theme, dropping all other escape sequences.
+func translateANSI(s string) string {
+	buf := []string{}
+	for _, seg := range ansi.Parse(s) {
+		style := seg.Style
+		style.Fg = paletteColor(style.Fg)
+		style.Bg = paletteColor(style.Bg)
+		buf = append(buf, paint(style, seg.Text))
+	}
+	return strings.Join(buf, "")
+}
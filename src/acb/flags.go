@@ -0,0 +1,122 @@
+package dockerlogs
+
+import (
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Flags holds the command line flags shared by docker-logs and humanlog.
+type Flags struct {
+	Profile     *string
+	ANSI        *string
+	First       *[]string
+	Last        *[]string
+	Fields      *[]string
+	HideFields  *[]string
+	Block       *[]string
+	MinLevel    *string
+	Where       *string
+	From        *string
+	To          *string
+	Trace       *[]string
+	TraceFollow *bool
+	Grep        *string
+	Highlights  *[]string
+	Before      *int
+	After       *int
+	Context     *int
+	Collapse    *bool
+	CollapseFor *time.Duration
+	InferLevels *bool
+	Output      *string
+	Template    *string
+	Relative    *string
+	TimeZone    *string
+	Precision   *string
+	Color       *string
+	Theme       *string
+	Wrap        *string
+
+	// blockSet is true if --block was given, possibly empty to show no
+	// fields on their own lines.
+	blockSet bool
+	// contextSet is true if --context was given, possibly 0 to override
+	// --before-context and --after-context.
+	contextSet bool
+}
+
+// NewFlags adds the shared flags to app.
+func NewFlags(app *kingpin.Application) *Flags {
+	f := &Flags{}
+	f.Profile = app.Flag("profile", "Use the flags and containers of a profile defined in profiles.json; other flags override them.").Short('p').PlaceHolder("NAME").String()
+	f.ANSI = app.Flag("ansi", "What to do with escape sequences in unstructured lines: strip, keep or theme (recolor).").Default(string(ANSIStrip)).Enum(ANSIModes...)
+	f.First = app.Flag("first", "Show these fields first, in the given order (comma separated, repeatable).").PlaceHolder("FIELD,...").Strings()
+	f.Last = app.Flag("last", "Show these fields last, in the given order.").PlaceHolder("FIELD,...").Strings()
+	f.Fields = app.Flag("fields", "Only output these fields; patterns such as http.* or *.id match nested fields.").PlaceHolder("FIELD,...").Strings()
+	f.HideFields = app.Flag("hide-fields", "Do not output these fields.").PlaceHolder("FIELD,...").Strings()
+	f.Block = app.Flag("block", "Show these fields on their own lines, none if empty (default: error and stack trace fields).").PlaceHolder("FIELD,...").
+		Action(func(*kingpin.ParseContext) error {
+			f.blockSet = true
+			return nil
+		}).Strings()
+	f.MinLevel = app.Flag("min-level", "Only show logs of at least this level, e.g. warn or E. Lines without a level are hidden.").PlaceHolder("LEVEL").String()
+	f.Where = app.Flag("where", "Only show logs matching an expression, e.g. 'level>=warn && container=~\"api.*\" && status>=500 && !msg~\"healthcheck\"'.").PlaceHolder("EXPR").String()
+	f.From = app.Flag("from", "Only show logs logged at or after this time, e.g. 10:42:00 or 2017-01-01T10:42:00Z.").PlaceHolder("TIME").String()
+	f.To = app.Flag("to", "Only show logs logged up to this time, including the whole second (or minute) given.").PlaceHolder("TIME").String()
+	f.Trace = app.Flag("trace", "Only show logs with this id, e.g. a request_id, in any field; hops between containers show their latency (repeatable).").PlaceHolder("ID").Strings()
+	f.TraceFollow = app.Flag("trace-follow", "Also trace the ids of the logs found, so that logs whose parent_id is a traced span_id are shown.").Bool()
+	f.Grep = app.Flag("grep", "Only show logs whose message, caller or a key=value field matches this regular expression, with the matches highlighted.").PlaceHolder("REGEXP").String()
+	f.Highlights = app.Flag("highlight", "Highlight text, a /regexp/ or a field=value in its own color, without filtering (repeatable).").PlaceHolder("RULE").Strings()
+	f.Before = app.Flag("before-context", "Also show this many logs before each --grep match.").Short('B').PlaceHolder("N").Int()
+	f.After = app.Flag("after-context", "Also show this many logs after each --grep match.").Short('A').PlaceHolder("N").Int()
+	f.Context = app.Flag("context", "Also show this many logs before and after each --grep match, overriding -A and -B.").Short('C').PlaceHolder("N").
+		Action(func(*kingpin.ParseContext) error {
+			f.contextSet = true
+			return nil
+		}).Int()
+	f.Collapse = app.Flag("collapse", "Fold repeats of a log, ignoring numbers and ids, into one line with their count.").Bool()
+	f.CollapseFor = app.Flag("collapse-timeout", "Show the count of repeats held by --collapse after this long.").Default(DefaultCollapseTimeout.String()).Duration()
+	f.InferLevels = app.Flag("infer-levels", "Guess the level of lines which do not declare one from keywords such as ERROR or panic:, shown in lower case.").Bool()
+	f.Output = app.Flag("output", "Output format: text, json, logfmt or csv (normalised records), or raw (lines as logged).").Short('o').Default(OutputText).Enum(OutputFormats...)
+	f.Template = app.Flag("template", "Print each log using a Go template instead, e.g. '{{.Container}} {{.Time | ms}} {{level .Level}} {{.Msg}} {{.Fields.request_id}}'.").PlaceHolder("TEMPLATE").String()
+	f.Relative = app.Flag("relative", "Show the time since the first or the previous log instead of the timestamp, to the millisecond.").Enum(RelativeModes...)
+	f.TimeZone = app.Flag("tz", "Show times in this zone: local, UTC or a name such as Europe/Paris (default: as logged).").PlaceHolder("ZONE").String()
+	f.Precision = app.Flag("precision", "Show times to this precision: s, ms, us or ns (default: s for timestamps, as logged otherwise).").Enum(Precisions...)
+	f.Color = app.Flag("color", "Color the output: auto (if stdout is a terminal and $NO_COLOR is not set), always or never.").Default(ColorAuto).Enum(ColorModes...)
+	f.Theme = app.Flag("theme", "Color theme: dark, light or one defined in themes.json.").Default(DarkTheme.Name).String()
+	f.Wrap = app.Flag("wrap", "What to do with logs wider than the terminal: none, truncate, soft (wrap onto indented lines) or fields (one field per line).").Default(string(WrapNone)).Enum(WrapModes...)
+	return f
+}
+
+// FieldOrder returns the order given by --first, --last and --block; fields
+// are shown on their own lines as in DefaultFieldOrder unless --block is
+// given.
+func (f *Flags) FieldOrder() FieldOrder {
+	order := FieldOrder{
+		First: SplitList(*f.First),
+		Last:  SplitList(*f.Last),
+		Block: SplitList(*f.Block),
+	}
+	if !f.blockSet {
+		order.Block = DefaultFieldOrder.Block
+	}
+	return order
+}
+
+// Projection returns the fields selected by --fields and --hide-fields.
+func (f *Flags) Projection() FieldProjection {
+	return FieldProjection{
+		Include: SplitList(*f.Fields),
+		Exclude: SplitList(*f.HideFields),
+	}
+}
+
+// GrepContext returns the number of logs to show before and after each --grep
+// match, which --context sets both of when given.
+func (f *Flags) GrepContext() (before, after int) {
+	if f.contextSet {
+		return *f.Context, *f.Context
+	}
+	return *f.Before, *f.After
+}
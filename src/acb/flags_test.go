@@ -0,0 +1,53 @@
+package dockerlogs
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Ensure an empty --block turns off the default block fields.
+func TestFlags_FieldOrder(t *testing.T) {
+	var tests = []struct {
+		args []string
+		exp  []string
+	}{
+		{args: []string{}, exp: DefaultFieldOrder.Block},
+		{args: []string{"--block=stack,cause"}, exp: []string{"stack", "cause"}},
+		{args: []string{"--block="}, exp: []string{}},
+	}
+	for i, tt := range tests {
+		app := kingpin.New("test", "")
+		flags := NewFlags(app)
+		if _, err := app.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := flags.FieldOrder().Block; !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("%d. %q: exp=%q got=%q", i, tt.args, tt.exp, got)
+		}
+	}
+}
+
+// Ensure --context overrides -A and -B, even when 0.
+func TestFlags_GrepContext(t *testing.T) {
+	var tests = []struct {
+		args          []string
+		before, after int
+	}{
+		{args: []string{}},
+		{args: []string{"-B", "2", "-A", "1"}, before: 2, after: 1},
+		{args: []string{"-B", "2", "-C", "3"}, before: 3, after: 3},
+		{args: []string{"-B", "2", "-A", "1", "-C", "0"}},
+	}
+	for i, tt := range tests {
+		app := kingpin.New("test", "")
+		flags := NewFlags(app)
+		if _, err := app.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if before, after := flags.GrepContext(); before != tt.before || after != tt.after {
+			t.Errorf("%d. %q: exp=%d,%d got=%d,%d", i, tt.args, tt.before, tt.after, before, after)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"acb/logparsers/layout"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Format is a named log line parser. Parse returns nil if the line is not
+// in the format.
+type Format struct {
+	Name  string
+	Parse func(l string) *Log
+}
+
+// RawFormat treats every line as an unstructured message.
+var RawFormat = &Format{"raw", parseRawLog}
+
+func layoutFormat(lay *layout.Layout) *Format {
+	return &Format{lay.Name, func(l string) *Log { return parseLayout(lay, l) }}
+}
+
+// builtinFormats lists the formats in the order in which they are tried.
+// Layouts are tried before logfmt, as the latter also accepts lines such as
+// "WARNING:root:a=b".
+var builtinFormats = []*Format{
+	{"json", parseJsonLog},
+	{"syslog", parseSyslogLog},
+	layoutFormat(layout.Glog),
+	layoutFormat(layout.Python),
+	layoutFormat(layout.Log4j),
+	layoutFormat(layout.CLF),
+	{"logfmt", parseKeyValueLog},
+}
+
+// formatsFor returns the user defined parse rules which apply to the source,
+// followed by the builtin formats.
+func formatsFor(src Source) []*Format {
+	formats := []*Format{}
+	for _, r := range parseRules {
+		if r.Matches(src) {
+			formats = append(formats, r.Format())
+		}
+	}
+	return append(formats, builtinFormats...)
+}
+
+// LookupFormat returns the builtin format or user defined parse rule with the
+// given name.
+func LookupFormat(name string) (*Format, error) {
+	if name == RawFormat.Name {
+		return RawFormat, nil
+	}
+	for _, r := range parseRules {
+		if r.Layout.Name == name {
+			return r.Format(), nil
+		}
+	}
+	for _, f := range builtinFormats {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	names := []string{RawFormat.Name}
+	for _, f := range formatsFor(Source{}) {
+		names = append(names, f.Name)
+	}
+	return nil, fmt.Errorf("unknown format %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// DefaultDetectLines is the number of lines used to detect the format of a
+// source.
+const DefaultDetectLines = 50
+
+// SourceParser parses the lines of a single source. Unless the format has
+// been pinned, every format is tried for the first DetectLines lines, after
+// which the source is pinned to the format which parsed the most of them.
+// Lines which can not be parsed by the pinned format are treated as raw.
+type SourceParser struct {
+	Source      Source
+	DetectLines int
+	ANSI        ANSIMode
+
+	// InferLevels assigns a level to lines which do not declare one, see
+	// DefaultLevelRules.
+	InferLevels bool
+
+	format *Format
+	counts map[string]int
+	seen   int
+}
+
+// NewSourceParser returns a parser for the source; format may be empty to
+// detect the format automatically.
+func NewSourceParser(src Source, format string) (*SourceParser, error) {
+	p := &SourceParser{
+		Source:      src,
+		DetectLines: DefaultDetectLines,
+		ANSI:        ANSIStrip,
+		counts:      map[string]int{},
+	}
+	if format != "" {
+		f, err := LookupFormat(format)
+		if err != nil {
+			return nil, err
+		}
+		p.format = f
+	}
+	return p, nil
+}
+
+// Format returns the name of the pinned format, or "" while still detecting.
+func (p *SourceParser) Format() string {
+	if p.format == nil {
+		return ""
+	}
+	return p.format.Name
+}
+
+// Parse parses the line with any escape sequences stripped; the sequences are
+// only kept (or translated) in the message of raw lines.
+func (p *SourceParser) Parse(l string) *Log {
+	stripped := ansi.Strip(l)
+	log, format := p.parse(stripped)
+	if stripped != l && format == RawFormat {
+		switch p.ANSI {
+		case ANSIKeep:
+			log.Msg = l
+		case ANSITheme:
+			log.Msg = translateANSI(l)
+		}
+	}
+	if p.InferLevels {
+		inferLevel(log)
+	}
+	return log
+}
+
+func (p *SourceParser) parse(l string) (*Log, *Format) {
+	if p.format != nil {
+		if log := p.format.Parse(l); log != nil {
+			return log, p.format
+		}
+		return parseRawLog(l), RawFormat
+	}
+
+	formats := formatsFor(p.Source)
+	log, format := parseFirst(formats, l)
+	p.counts[format.Name]++
+	p.seen++
+	if p.seen >= p.DetectLines {
+		p.format = dominantFormat(formats, p.counts)
+	}
+	return log, format
+}
+
+// dominantFormat returns the structured format which parsed the most lines,
+// preferring earlier formats on a tie, or RawFormat if none did.
+func dominantFormat(formats []*Format, counts map[string]int) *Format {
+	best := RawFormat
+	for _, f := range formats {
+		if counts[f.Name] > counts[best.Name] || (best == RawFormat && counts[f.Name] > 0) {
+			best = f
+		}
+	}
+	return best
+}
+
+func parseFirst(formats []*Format, l string) (*Log, *Format) {
+	for _, f := range formats {
+		if log := f.Parse(l); log != nil {
+			return log, f
+		}
+	}
+	return parseRawLog(l), RawFormat
+}
+
+// FormatOverrides maps container name glob patterns onto format names, as
+// given by --format web=json
+type FormatOverrides map[string]string
+
+// Lookup returns the format pinned for the source, or "" if none is.
+// Exact name matches take precedence over patterns, and longer patterns
+// over shorter ones.
+func (o FormatOverrides) Lookup(src Source) string {
+	if f, ok := o[src.Name]; ok {
+		return f
+	}
+	patterns := []string{}
+	for pattern := range o {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, src.Name); ok {
+			return o[pattern]
+		}
+	}
+	return ""
+}
+
+// Validate returns an error if any of the formats are unknown.
+func (o FormatOverrides) Validate() error {
+	for _, f := range o {
+		if _, err := LookupFormat(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
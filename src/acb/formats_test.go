@@ -0,0 +1,79 @@
+package dockerlogs
+
+import "testing"
+
+// Ensure the dominant format is pinned after the detection window, and that
+// lines of other formats are no longer parsed once pinned.
+func TestSourceParser_Detect(t *testing.T) {
+	p, err := NewSourceParser(Source{Name: "web"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.DetectLines = 4
+
+	for _, l := range []string{
+		`starting up`,
+		`level=info msg=listening port=80`,
+		`level=info msg=ready`,
+		`level=warn msg=slow`,
+	} {
+		p.Parse(l)
+	}
+	if p.Format() != "logfmt" {
+		t.Fatalf("expected logfmt to be pinned, got %q", p.Format())
+	}
+
+	log := p.Parse(`{"level":"error","msg":"looks like json"}`)
+	if log.Level != UNKNOWN || log.Msg != `{"level":"error","msg":"looks like json"}` {
+		t.Errorf("expected json line to be treated as raw, got %#v", log)
+	}
+}
+
+// Ensure sources which never produce a structured line are pinned as raw.
+func TestSourceParser_DetectRaw(t *testing.T) {
+	p, _ := NewSourceParser(Source{}, "")
+	p.DetectLines = 2
+	p.Parse(`hello`)
+	p.Parse(`world`)
+	if p.Format() != "raw" {
+		t.Errorf("expected raw to be pinned, got %q", p.Format())
+	}
+}
+
+func TestFormatOverrides_Lookup(t *testing.T) {
+	o := FormatOverrides{"web": "json", "nginx*": "clf", "*": "logfmt"}
+	for name, exp := range map[string]string{
+		"web":     "json",
+		"nginx-1": "clf",
+		"db":      "logfmt",
+	} {
+		if got := o.Lookup(Source{Name: name}); got != exp {
+			t.Errorf("%s: expected %q got %q", name, exp, got)
+		}
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (FormatOverrides{"web": "yaml"}).Validate(); err == nil {
+		t.Errorf("expected unknown format error")
+	}
+}
+
+// Ensure escape sequences do not prevent structured lines from being parsed,
+// and are only kept in the message of raw lines when asked to.
+func TestSourceParser_ANSI(t *testing.T) {
+	p, _ := NewSourceParser(Source{}, "")
+	log := p.Parse("level=\x1b[33mwarn\x1b[0m msg=hi")
+	if log.Level != WARNING || log.Msg != "hi" {
+		t.Errorf("expected stripped logfmt line, got %#v", log)
+	}
+
+	raw := "\x1b[31mnpm ERR!\x1b[0m failed"
+	if log := p.Parse(raw); log.Msg != "npm ERR! failed" {
+		t.Errorf("expected stripped message, got %q", log.Msg)
+	}
+	p.ANSI = ANSIKeep
+	if log := p.Parse(raw); log.Msg != raw {
+		t.Errorf("expected kept message, got %q", log.Msg)
+	}
+}
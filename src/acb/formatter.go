@@ -0,0 +1,215 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"regexp"
+	"strings"
+)
+
+// FieldOrder controls which context fields are shown, and where. Fields
+// which are not mentioned keep the order in which they were logged.
+type FieldOrder struct {
+	// First lists fields which are shown directly after the message.
+	First []string
+	// Last lists fields which are shown at the end of the line.
+	Last []string
+	// Block lists fields which are shown on their own continuation lines,
+	// e.g. errors and stack traces.
+	Block []string
+}
+
+// DefaultFieldOrder moves errors and stack traces onto their own lines.
+var DefaultFieldOrder = FieldOrder{
+	Block: []string{"error", "err", "exception", "stack", "stacktrace", "stack_trace"},
+}
+
+func indexOf(names []string, key string) int {
+	for i, n := range names {
+		if n == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Arrange splits the fields into those shown inline (in display order) and
+// those shown on continuation lines.
+func (o FieldOrder) Arrange(fields KeyValues) (inline, block KeyValues) {
+	first := make([]KeyValues, len(o.First))
+	last := make([]KeyValues, len(o.Last))
+	middle := KeyValues{}
+	for _, kv := range fields {
+		if indexOf(o.Block, kv.Key) != -1 {
+			block = append(block, kv)
+		} else if i := indexOf(o.First, kv.Key); i != -1 {
+			first[i] = append(first[i], kv)
+		} else if i := indexOf(o.Last, kv.Key); i != -1 {
+			last[i] = append(last[i], kv)
+		} else {
+			middle = append(middle, kv)
+		}
+	}
+
+	for _, kvs := range first {
+		inline = append(inline, kvs...)
+	}
+	inline = append(inline, middle...)
+	for _, kvs := range last {
+		inline = append(inline, kvs...)
+	}
+	return inline, block
+}
+
+// WrapMode controls how logs which are wider than the terminal are shown.
+type WrapMode string
+
+const (
+	// WrapNone prints logs on a single line, however long
+	WrapNone WrapMode = "none"
+	// WrapTruncate cuts logs off at the terminal width
+	WrapTruncate WrapMode = "truncate"
+	// WrapSoft continues logs on the following lines, between words and
+	// fields where possible
+	WrapSoft WrapMode = "soft"
+	// WrapFields shows each field of a log which does not fit on its own
+	// line
+	WrapFields WrapMode = "fields"
+)
+
+// WrapModes lists the valid values of --wrap
+var WrapModes = []string{string(WrapNone), string(WrapTruncate), string(WrapSoft), string(WrapFields)}
+
+// Formatter renders logs for display on a terminal.
+type Formatter struct {
+	Fields FieldOrder
+
+	// Indent is the number of columns continuation lines are indented by,
+	// i.e. the width of anything printed before the formatted log.
+	Indent int
+
+	// Width is the width of the terminal, including Indent; logs wider than
+	// it are wrapped according to Wrap. 0 disables wrapping.
+	Width int
+	Wrap  WrapMode
+
+	// Highlight, if set, marks its matches in messages and field values.
+	Highlight *regexp.Regexp
+	// Highlights restyles matching text and fields, see HighlightRule.
+	Highlights []*HighlightRule
+}
+
+// DefaultFormatter is used by Log.Format
+var DefaultFormatter = &Formatter{
+	Fields: DefaultFieldOrder,
+}
+
+func (f *Formatter) formatField(key, value string) string {
+	if h := f.fieldRule(key, value); h != nil {
+		return paint(h.style(), key+"="+ansi.Strip(value))
+	}
+	return render(f.highlightSegments([]ansi.Segment{
+		{Text: key, Style: theme.Key},
+		{Text: "=", Style: theme.Separator},
+		{Text: value, Style: theme.Value},
+	}))
+}
+
+// minWrapWidth is the fewest columns left beside the prefix for logs to be
+// wrapped or truncated.
+const minWrapWidth = 20
+
+// fill packs words into lines of at most first columns for the first line
+// and rest for the others, cutting words which do not fit on a line.
+func fill(words []string, first, rest int) []string {
+	lines := []string{}
+	line, width, max := "", 0, first
+	for _, word := range words {
+		w := ansi.Width(word)
+		if width > 0 && width+1+w > max {
+			lines = append(lines, line)
+			line, width, max = "", 0, rest
+		}
+		if width > 0 {
+			line += " "
+			width++
+		}
+		for width+w > max {
+			head, tail := ansi.Cut(word, max-width)
+			lines = append(lines, line+head)
+			line, width, max = "", 0, rest
+			word, w = tail, ansi.Width(tail)
+		}
+		line += word
+		width += w
+	}
+	return append(lines, line)
+}
+
+func (f *Formatter) Format(l *Log) string {
+	head := []string{}
+	if l.LevelInferred {
+		head = append(head, InferredLevelToColorString(l.Level))
+	} else {
+		head = append(head, LogLevelToColorString(l.Level))
+	}
+	if l.Caller != "" {
+		head = append(head, f.highlight(theme.Caller, l.Caller))
+	}
+	inline, block := f.Fields.Arrange(l.Context)
+	fields := []string{}
+	for _, x := range inline {
+		fields = append(fields, f.formatField(x.Key, x.Value.String()))
+	}
+
+	// continuation lines are aligned with the message, the remaining lines
+	// of multi-line values (such as stack traces) are indented further
+	indent := strings.Repeat(" ", f.Indent+4)
+	first, rest := f.Width-f.Indent, f.Width-f.Indent-4
+	wrap := f.Wrap
+	if f.Width <= 0 || rest < minWrapWidth {
+		// no room to wrap into
+		wrap = WrapNone
+	}
+
+	line := head
+	if l.Msg != "" {
+		line = append(line, f.highlight(theme.Msg, l.Msg))
+	}
+	line = append(line, fields...)
+	s := strings.Join(line, " ")
+	switch wrap {
+	case WrapTruncate:
+		s = ansi.Truncate(s, first, "…")
+	case WrapSoft, WrapFields:
+		msgWords := append([]string{}, head...)
+		msgWords = append(msgWords, words(f.highlightSegments([]ansi.Segment{{Text: l.Msg, Style: theme.Msg}}))...)
+		if wrap == WrapSoft {
+			s = strings.Join(fill(append(msgWords, fields...), first, rest), "\n"+indent)
+		} else if ansi.Width(s) > first {
+			lines := fill(msgWords, first, rest)
+			for _, field := range fields {
+				lines = append(lines, fill([]string{field}, rest, rest)...)
+			}
+			s = strings.Join(lines, "\n"+indent)
+		}
+	}
+
+	for _, x := range block {
+		values := strings.Split(strings.TrimRight(x.Value.String(), "\n"), "\n")
+		lines := []string{f.formatField(x.Key, values[0])}
+		for _, v := range values[1:] {
+			lines = append(lines, "  "+f.highlight(theme.Value, v))
+		}
+		for _, line := range lines {
+			switch wrap {
+			case WrapTruncate:
+				s += "\n" + indent + ansi.Truncate(line, rest, "…")
+			case WrapSoft, WrapFields:
+				s += "\n" + indent + strings.Join(fill([]string{line}, rest, rest), "\n"+indent)
+			default:
+				s += "\n" + indent + line
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func keys(kvs KeyValues) []string {
+	k := []string{}
+	for _, kv := range kvs {
+		k = append(k, kv.Key)
+	}
+	return k
+}
+
+// Ensure fields keep their logged order unless pinned or blocked.
+func TestFieldOrder_Arrange(t *testing.T) {
+	fields := KeyValues{}
+	for _, k := range []string{"z", "stack", "a", "request_id", "pid", "m", "error"} {
+		fields = append(fields, KeyValue{k, StringValue(k)})
+	}
+
+	inline, block := FieldOrder{}.Arrange(fields)
+	if exp := []string{"z", "stack", "a", "request_id", "pid", "m", "error"}; !reflect.DeepEqual(exp, keys(inline)) {
+		t.Errorf("expected logged order %v, got %v", exp, keys(inline))
+	}
+	if len(block) != 0 {
+		t.Errorf("expected no block fields, got %v", keys(block))
+	}
+
+	inline, block = FieldOrder{
+		First: []string{"request_id", "missing"},
+		Last:  []string{"a", "z"},
+		Block: DefaultFieldOrder.Block,
+	}.Arrange(fields)
+	if exp := []string{"request_id", "pid", "m", "a", "z"}; !reflect.DeepEqual(exp, keys(inline)) {
+		t.Errorf("expected inline %v, got %v", exp, keys(inline))
+	}
+	if exp := []string{"stack", "error"}; !reflect.DeepEqual(exp, keys(block)) {
+		t.Errorf("expected block %v, got %v", exp, keys(block))
+	}
+}
+
+// Ensure long logs are truncated or wrapped to the terminal width, with
+// continuation lines indented under the message.
+func TestFormatter_Wrap(t *testing.T) {
+	SetColors(DarkTheme, ansi.NoColor)
+	defer SetColors(DarkTheme, ansi.TrueColor)
+
+	log := &Log{
+		Level: INFO,
+		Msg:   "request handled in time",
+		Context: KeyValues{
+			{"path", StringValue("/api/v1/users")},
+			{"status", IntValue(200)},
+			{"stack", StringValue("main.go:10\nserver.go:20")},
+		},
+	}
+	var tests = []struct {
+		wrap WrapMode
+		exp  string
+	}{
+		{wrap: WrapNone, exp: "INF request handled in time path=/api/v1/users status=200\n" +
+			"      stack=main.go:10\n" +
+			"        server.go:20"},
+		{wrap: WrapTruncate, exp: "INF request handled in time path=/api/v1/u…\n" +
+			"      stack=main.go:10\n" +
+			"        server.go:20"},
+		{wrap: WrapSoft, exp: "INF request handled in time\n" +
+			"      path=/api/v1/users status=200\n" +
+			"      stack=main.go:10\n" +
+			"        server.go:20"},
+		{wrap: WrapFields, exp: "INF request handled in time\n" +
+			"      path=/api/v1/users\n" +
+			"      status=200\n" +
+			"      stack=main.go:10\n" +
+			"        server.go:20"},
+	}
+	for i, tt := range tests {
+		f := &Formatter{Fields: DefaultFieldOrder, Indent: 2, Width: 45, Wrap: tt.wrap}
+		if got := f.Format(log); got != tt.exp {
+			t.Errorf("%d. %s: exp\n%s\ngot\n%s", i, tt.wrap, tt.exp, got)
+		}
+	}
+
+	// a wide prefix leaves no room to wrap into
+	f := &Formatter{Fields: DefaultFieldOrder, Indent: 30, Width: 45, Wrap: WrapSoft}
+	if got := strings.SplitN(f.Format(log), "\n", 2)[0]; got != "INF request handled in time path=/api/v1/users status=200" {
+		t.Errorf("expected no wrapping beside a wide prefix, got\n%s", got)
+	}
+
+	if got := fill([]string{"abcdefghij"}, 4, 3); !reflect.DeepEqual(got, []string{"abcd", "efg", "hij"}) {
+		t.Errorf("expected long words to be cut, got %q", got)
+	}
+}
+
+// Ensure matches are highlighted in messages and field values.
+func TestFormatter_Highlight(t *testing.T) {
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	theme := *DarkTheme
+	theme.Match = ansi.Style{Bold: true}
+	SetColors(&theme, ansi.Color16)
+
+	f := &Formatter{Highlight: regexp.MustCompile("time(out)?")}
+	log := &Log{Level: ERROR, Msg: "read timeout", Context: KeyValues{{"after", StringValue("5s timeout")}}}
+	got := ansi.Strip(f.Format(log))
+	if exp := "ERR read timeout after=5s timeout"; got != exp {
+		t.Errorf("exp=%q got=%q", exp, got)
+	}
+	if n := strings.Count(f.Format(log), "\x1b[1mtimeout\x1b[0m"); n != 2 {
+		t.Errorf("expected 2 highlighted matches, got %d in %q", n, f.Format(log))
+	}
+	f = &Formatter{Highlight: regexp.MustCompile("after=5s|read time")}
+	if got := f.Format(log); !strings.Contains(got, "\x1b[1mafter\x1b[0m\x1b[1m=\x1b[0m\x1b[1m5s\x1b[0m") {
+		t.Errorf("expected the key and value to be highlighted, got %q", got)
+	}
+	f.Wrap, f.Width = WrapSoft, 30
+	if got := f.Format(log); !strings.Contains(got, "\x1b[1mread\x1b[0m \x1b[1mtime\x1b[0m") {
+		t.Errorf("expected a match spanning words to be highlighted when wrapped, got %q", got)
+	}
+}
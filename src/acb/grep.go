@@ -0,0 +1,128 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"regexp"
+)
+
+// ring holds the most recent records, up to its capacity.
+type ring struct {
+	records []*Record
+	start   int
+	n       int
+}
+
+func newRing(size int) *ring {
+	return &ring{records: make([]*Record, size)}
+}
+
+// push adds a record, returning false if the oldest record (or r itself, if
+// the ring has no capacity) had to be dropped to make room.
+func (b *ring) push(r *Record) bool {
+	if len(b.records) == 0 {
+		return false
+	}
+	if b.n == len(b.records) {
+		b.records[b.start] = r
+		b.start = (b.start + 1) % len(b.records)
+		return false
+	}
+	b.records[(b.start+b.n)%len(b.records)] = r
+	b.n++
+	return true
+}
+
+// drain removes and returns the records, oldest first.
+func (b *ring) drain() []*Record {
+	records := make([]*Record, 0, b.n)
+	for i := 0; i < b.n; i++ {
+		records = append(records, b.records[(b.start+i)%len(b.records)])
+		b.records[(b.start+i)%len(b.records)] = nil
+	}
+	b.start, b.n = 0, 0
+	return records
+}
+
+// grepContext is the state of the context of one container, or of the
+// merged stream.
+type grepContext struct {
+	before  *ring
+	after   int
+	printed bool
+	dropped bool
+}
+
+// Grep selects the records which match a pattern, along with records logged
+// before and after them like grep -B and -A.
+type Grep struct {
+	Pattern *regexp.Regexp
+	Before  int
+	After   int
+	// Merged takes the context from the merged stream of all containers,
+	// rather than from the container of the matching record.
+	Merged bool
+	// Fields selects the fields which are shown, and so matched.
+	Fields FieldProjection
+
+	contexts map[string]*grepContext
+}
+
+func (g *Grep) context(r *Record) *grepContext {
+	key := r.Source.Name
+	if g.Merged {
+		key = ""
+	}
+	if g.contexts == nil {
+		g.contexts = map[string]*grepContext{}
+	}
+	c, ok := g.contexts[key]
+	if !ok {
+		c = &grepContext{before: newRing(g.Before)}
+		g.contexts[key] = c
+	}
+	return c
+}
+
+// Match returns true if the pattern matches the text shown for the record,
+// and so can be highlighted: its message, caller or one of its fields as
+// key=value if it is selected by g.Fields, or the line if it was not parsed.
+func (g *Grep) Match(r *Record) bool {
+	if r.Log == nil {
+		return g.Pattern.MatchString(ansi.Strip(r.Line))
+	}
+	for _, s := range []string{r.Log.Msg, r.Log.Caller} {
+		if g.Pattern.MatchString(ansi.Strip(s)) {
+			return true
+		}
+	}
+	for _, kv := range g.Fields.Apply(r.Log.Context) {
+		if g.Pattern.MatchString(kv.Key + "=" + ansi.Strip(kv.Value.String())) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the records to show now that r has been received: nothing,
+// r as context after a match, or the context before r followed by r if it
+// matches. gap is true if records were skipped since the last ones shown
+// from the same context, which grep marks with "--".
+func (g *Grep) Filter(r *Record) (records []*Record, gap bool) {
+	c := g.context(r)
+	switch {
+	case g.Match(r):
+		records = append(c.before.drain(), r)
+		c.after = g.After
+	case c.after > 0:
+		records = []*Record{r}
+		c.after--
+	default:
+		if !c.before.push(r) {
+			c.dropped = true
+		}
+		return nil, false
+	}
+	gap = c.printed && c.dropped
+	c.printed, c.dropped = true, false
+	return records, gap
+}
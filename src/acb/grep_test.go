@@ -0,0 +1,81 @@
+package dockerlogs
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Ensure matches are shown with the context before and after them, taken
+// from their own container unless merged.
+func TestGrep_Filter(t *testing.T) {
+	lines := []string{
+		"web 1", "db 1", "web 2", "web timeout", "db 2", "web 3", "web 4",
+		"web 5", "web 6", "web timeout", "db timeout",
+	}
+	run := func(g *Grep) []string {
+		out := []string{}
+		for _, line := range lines {
+			r := &Record{Source: Source{Name: strings.Fields(line)[0]}, Line: line}
+			records, gap := g.Filter(r)
+			if gap {
+				out = append(out, "--")
+			}
+			for _, r := range records {
+				out = append(out, r.Line)
+			}
+		}
+		return out
+	}
+
+	pattern := regexp.MustCompile("timeout")
+	exp := []string{
+		"web 1", "web 2", "web timeout", "web 3",
+		"--", "web 5", "web 6", "web timeout",
+		"db 1", "db 2", "db timeout",
+	}
+	if got := run(&Grep{Pattern: pattern, Before: 2, After: 1}); !reflect.DeepEqual(exp, got) {
+		t.Errorf("exp=%q\ngot=%q", exp, got)
+	}
+
+	exp = []string{
+		"web 2", "web timeout", "db 2",
+		"--", "web 6", "web timeout", "db timeout",
+	}
+	if got := run(&Grep{Pattern: pattern, Before: 1, After: 1, Merged: true}); !reflect.DeepEqual(exp, got) {
+		t.Errorf("exp=%q\ngot=%q", exp, got)
+	}
+}
+
+// Ensure the text shown for parsed logs is matched, rather than the line.
+func TestGrep_Match(t *testing.T) {
+	g := &Grep{Pattern: regexp.MustCompile(`^status=5|level|main\.go`)}
+	var tests = []struct {
+		log *Log
+		exp bool
+	}{
+		{log: &Log{Level: ERROR, Msg: "failed", Context: KeyValues{{"status", IntValue(502)}}}, exp: true},
+		{log: &Log{Level: INFO, Msg: "ok", Context: KeyValues{{"status", IntValue(200)}}}, exp: false},
+		{log: &Log{Level: INFO, Msg: "ok", Caller: "main.go:12"}, exp: true},
+	}
+	for i, tt := range tests {
+		r := &Record{Line: `{"level":"info","status":200}`, Log: tt.log}
+		if got := g.Match(r); got != tt.exp {
+			t.Errorf("%d. %v: exp=%v got=%v", i, tt.log, tt.exp, got)
+		}
+	}
+	if !g.Match(&Record{Line: "level 1"}) {
+		t.Errorf("expected unparsed lines to be matched")
+	}
+
+	g = &Grep{Pattern: regexp.MustCompile(`secret`), Fields: FieldProjection{Exclude: []string{"password"}}}
+	r := &Record{Log: &Log{Msg: "login", Context: KeyValues{{"user", StringValue("bob")}, {"password", StringValue("secret")}}}}
+	if g.Match(r) {
+		t.Errorf("expected hidden fields not to be matched")
+	}
+	g.Fields = FieldProjection{}
+	if !g.Match(r) {
+		t.Errorf("expected shown fields to be matched")
+	}
+}
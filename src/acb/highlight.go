@@ -0,0 +1,191 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// HighlightRule restyles parts of logs, without filtering them.
+type HighlightRule struct {
+	// Field restricts the rule to the field of this name, which is
+	// highlighted as a whole if its value matches Pattern.
+	Field   string
+	Pattern *regexp.Regexp
+	// Color is the index of the theme's Highlights style to use.
+	Color int
+}
+
+var highlightField = regexp.MustCompile(`^[A-Za-z_][\w.-]*=`)
+
+// ParseHighlightRule parses a --highlight rule: /REGEXP/, FIELD=VALUE or
+// literal text, which is matched anywhere in messages and field values.
+func ParseHighlightRule(spec string, color int) (*HighlightRule, error) {
+	rule := &HighlightRule{Color: color}
+	switch {
+	case spec == "":
+		return nil, fmt.Errorf("empty highlight rule")
+	case len(spec) > 2 && spec[0] == '/' && spec[len(spec)-1] == '/':
+		re, err := regexp.Compile(spec[1 : len(spec)-1])
+		if err != nil {
+			return nil, err
+		}
+		rule.Pattern = re
+	case highlightField.MatchString(spec):
+		i := strings.IndexByte(spec, '=')
+		rule.Field = spec[:i]
+		rule.Pattern = regexp.MustCompile("^" + regexp.QuoteMeta(spec[i+1:]) + "$")
+	default:
+		rule.Pattern = regexp.MustCompile(regexp.QuoteMeta(spec))
+	}
+	return rule, nil
+}
+
+// style returns the theme's style for the rule, cycling through the
+// Highlights styles.
+func (h *HighlightRule) style() ansi.Style {
+	if len(theme.Highlights) == 0 {
+		return theme.Match
+	}
+	return theme.Highlights[h.Color%len(theme.Highlights)]
+}
+
+// fieldRule returns the first field rule matching the field, if any.
+func (f *Formatter) fieldRule(key, value string) *HighlightRule {
+	for _, h := range f.Highlights {
+		if h.Field == key && h.Pattern.MatchString(ansi.Strip(value)) {
+			return h
+		}
+	}
+	return nil
+}
+
+type highlightSpan struct {
+	start, end int
+	style      ansi.Style
+}
+
+// highlight paints s in the style, the matches of f.Highlight in it in the
+// theme's Match style and those of the text rules in f.Highlights in the
+// theme's Highlights styles.
+func (f *Formatter) highlight(style ansi.Style, s string) string {
+	return render(f.highlightSegments([]ansi.Segment{{Text: s, Style: style}}))
+}
+
+// highlightSegments restyles the matches in the text of the segments, which
+// may span several of them, e.g. the key and value of a field. Where matches
+// overlap the earlier pattern wins. Escape sequences kept in the text are not
+// matched, nor split.
+func (f *Formatter) highlightSegments(segments []ansi.Segment) []ansi.Segment {
+	s := ""
+	offsets := make([][]int, len(segments))
+	for i, seg := range segments {
+		s += ansi.Strip(seg.Text)
+		offsets[i] = ansi.Offsets(seg.Text)
+	}
+	spans := []highlightSpan{}
+	add := func(re *regexp.Regexp, style ansi.Style) {
+	next:
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			for _, span := range spans {
+				if loc[0] < span.end && span.start < loc[1] {
+					continue next
+				}
+			}
+			spans = append(spans, highlightSpan{loc[0], loc[1], style})
+		}
+	}
+	if f.Highlight != nil {
+		add(f.Highlight, theme.Match)
+	}
+	for _, h := range f.Highlights {
+		if h.Field == "" {
+			add(h.Pattern, h.style())
+		}
+	}
+	if len(spans) == 0 {
+		return segments
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	out := []ansi.Segment{}
+	start := 0
+	for k, seg := range segments {
+		end := start + len(offsets[k])
+		if start == end {
+			out = append(out, seg)
+			continue
+		}
+		// raw returns the offset in the text of the segment of the i'th
+		// byte of s, with leading and trailing escapes in the outer pieces
+		raw := func(i int) int {
+			switch i {
+			case start:
+				return 0
+			case end:
+				return len(seg.Text)
+			}
+			return offsets[k][i-start]
+		}
+		for i := start; i < end; {
+			style, next := seg.Style, end
+			for _, span := range spans {
+				if span.start <= i && i < span.end {
+					style = span.style
+					if span.end < next {
+						next = span.end
+					}
+					break
+				}
+				if i < span.start && span.start < next {
+					next = span.start
+				}
+			}
+			out = append(out, ansi.Segment{Text: seg.Text[raw(i):raw(next)], Style: style})
+			i = next
+		}
+		start = end
+	}
+	return out
+}
+
+// render paints each segment in its style.
+func render(segments []ansi.Segment) string {
+	buf := ""
+	for _, seg := range segments {
+		buf += paint(seg.Style, seg.Text)
+	}
+	return buf
+}
+
+// words splits the text of the segments into words, each painted in the
+// styles of its parts.
+func words(segments []ansi.Segment) []string {
+	words := []string{}
+	word := ""
+	for _, seg := range segments {
+		part := ""
+		for _, r := range seg.Text {
+			if !unicode.IsSpace(r) {
+				part += string(r)
+				continue
+			}
+			word += paint(seg.Style, part)
+			if word != "" {
+				words = append(words, word)
+			}
+			word, part = "", ""
+		}
+		word += paint(seg.Style, part)
+	}
+	if word != "" {
+		words = append(words, word)
+	}
+	return words
+}
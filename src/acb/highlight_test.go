@@ -0,0 +1,103 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Ensure rules are parsed as regexps, fields or literal text.
+func TestParseHighlightRule(t *testing.T) {
+	for _, tt := range []struct {
+		spec    string
+		field   string
+		pattern string
+		err     bool
+	}{
+		{spec: "timeout", pattern: "timeout"},
+		{spec: "a.b (c)", pattern: `a\.b \(c\)`},
+		{spec: "/req-[0-9]+/", pattern: "req-[0-9]+"},
+		{spec: "user_id=1234", field: "user_id", pattern: "^1234$"},
+		{spec: "http.status=5.x", field: "http.status", pattern: `^5\.x$`},
+		{spec: "a == b", pattern: "a == b"},
+		{spec: "/", pattern: "/"},
+		{spec: "/(/", err: true},
+		{spec: "", err: true},
+	} {
+		rule, err := ParseHighlightRule(tt.spec, 0)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if rule.Field != tt.field || rule.Pattern.String() != tt.pattern {
+			t.Errorf("%q: exp=%q %q got=%q %q", tt.spec, tt.field, tt.pattern, rule.Field, rule.Pattern)
+		}
+	}
+}
+
+// Ensure each rule gets its own highlight color and field rules highlight
+// the whole field.
+func TestFormatter_Highlights(t *testing.T) {
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	theme := *DarkTheme
+	theme.Highlights = []ansi.Style{{Bold: true}, {Underline: true}, {Fg: &ansi.Color{Index: 1}}}
+	SetColors(&theme, ansi.Color16)
+
+	f := &Formatter{}
+	for i, spec := range []string{"/time(out)?/", "user_id=1234", "read"} {
+		rule, err := ParseHighlightRule(spec, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Highlights = append(f.Highlights, rule)
+	}
+	log := &Log{Level: ERROR, Msg: "read timeout", Context: KeyValues{
+		{"user_id", StringValue("1234")},
+		{"other_id", StringValue("1234")},
+	}}
+	got := f.Format(log)
+	if exp := "ERR read timeout user_id=1234 other_id=1234"; ansi.Strip(got) != exp {
+		t.Errorf("exp=%q got=%q", exp, ansi.Strip(got))
+	}
+	for _, exp := range []string{
+		"\x1b[1mtimeout\x1b[0m",
+		"\x1b[4muser_id=1234\x1b[0m",
+		"\x1b[31mread\x1b[0m",
+	} {
+		if !strings.Contains(got, exp) {
+			t.Errorf("expected %q in %q", exp, got)
+		}
+	}
+	if strings.Contains(got, "\x1b[4mother_id") {
+		t.Errorf("expected other_id not to be highlighted in %q", got)
+	}
+}
+
+// Ensure escape sequences kept in messages are neither matched nor split.
+func TestFormatter_HighlightEscapes(t *testing.T) {
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	theme := *DarkTheme
+	theme.Msg, theme.Match = ansi.Style{}, ansi.Style{Bold: true}
+	SetColors(&theme, ansi.Color16)
+
+	log := &Log{Msg: "\x1b[33mwarn\x1b[0m: disk full"}
+	for _, tt := range []struct {
+		pattern string
+		exp     string
+	}{
+		{pattern: "33m", exp: "\x1b[33mwarn\x1b[0m: disk full"},
+		{pattern: "n: d", exp: "\x1b[33mwar\x1b[1mn\x1b[0m: d\x1b[0misk full"},
+	} {
+		f := &Formatter{Highlight: regexp.MustCompile(tt.pattern)}
+		if got := f.Format(log); !strings.HasSuffix(got, " "+tt.exp) {
+			t.Errorf("%s: exp=%q got=%q", tt.pattern, tt.exp, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"fmt"
+	"regexp"
+)
+
+// LevelRule infers the level of a line which does not declare one, from a
+// keyword or pattern found in its message.
+type LevelRule struct {
+	Level  LogLevel
+	Regexp *regexp.Regexp
+}
+
+// DefaultLevelRules are tried in order, so more severe levels come first.
+// They are case sensitive to avoid matching e.g. "0 errors".
+var DefaultLevelRules = []LevelRule{
+	{FATAL, regexp.MustCompile(`\bFATAL\b|\bPANIC\b|(^|\s)panic:|^fatal error:`)},
+	{CRITICAL, regexp.MustCompile(`\bCRITICAL\b|\bCRIT\b`)},
+	{ERROR, regexp.MustCompile(`\bERROR\b|\bERR!|\b\w*(Exception|Error)\b|^Traceback \(most recent call last\)|(^|\s)error:`)},
+	{WARNING, regexp.MustCompile(`\bWARN(ING)?\b|\bDeprecationWarning\b`)},
+	{NOTICE, regexp.MustCompile(`\bNOTICE\b`)},
+	{INFO, regexp.MustCompile(`\bINFO\b`)},
+	{DEBUG, regexp.MustCompile(`\bDEBUG\b`)},
+	{TRACE, regexp.MustCompile(`\bTRACE\b`)},
+}
+
+var levelRules = DefaultLevelRules
+
+type levelRuleConfig struct {
+	Level  string `json:"level"`
+	Regexp string `json:"regexp"`
+}
+
+func compileLevelRules(configs []levelRuleConfig) ([]LevelRule, error) {
+	rules := []LevelRule{}
+	for _, c := range configs {
+		level, err := ParseLogLevel(c.Level)
+		if err != nil {
+			return nil, fmt.Errorf("infer rule: %v", err)
+		}
+		re, err := regexp.Compile(c.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("infer rule %s: %v", c.Level, err)
+		}
+		rules = append(rules, LevelRule{level, re})
+	}
+	return rules, nil
+}
+
+// inferLevel sets the level of a log which has none from the first level
+// rule matching its message.
+func inferLevel(log *Log) {
+	if log.Level != UNKNOWN {
+		return
+	}
+	msg := ansi.Strip(log.Msg)
+	for _, r := range levelRules {
+		if r.Regexp.MatchString(msg) {
+			log.Level = r.Level
+			log.LevelInferred = true
+			return
+		}
+	}
+}
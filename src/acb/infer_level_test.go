@@ -0,0 +1,28 @@
+package dockerlogs
+
+import "testing"
+
+// Ensure levels are only inferred for lines which do not declare one.
+func TestInferLevel(t *testing.T) {
+	var tests = []struct {
+		s        string
+		level    LogLevel
+		inferred bool
+	}{
+		{s: `panic: runtime error: index out of range`, level: FATAL, inferred: true},
+		{s: `Exception in thread "main" java.lang.NullPointerException`, level: ERROR, inferred: true},
+		{s: `npm ERR! code ELIFECYCLE`, level: ERROR, inferred: true},
+		{s: `2024/01/02 WARN disk almost full`, level: WARNING, inferred: true},
+		{s: `finished with 0 errors`, level: UNKNOWN},
+		{s: `level=info msg="ERROR in upstream"`, level: INFO},
+	}
+
+	p, _ := NewSourceParser(Source{}, "")
+	p.InferLevels = true
+	for i, tt := range tests {
+		log := p.Parse(tt.s)
+		if log.Level != tt.level || log.LevelInferred != tt.inferred {
+			t.Errorf("%d. %q: exp=%s (inferred=%v) got=%s (inferred=%v)", i, tt.s, tt.level, tt.inferred, log.Level, log.LevelInferred)
+		}
+	}
+}
@@ -1,32 +1,56 @@
 package dockerlogs
 
 import (
+	"acb/ansi"
 	"acb/logparsers/keyvalue"
-	"bytes"
-	"encoding/json"
+	"acb/logparsers/layout"
+	"acb/logparsers/syslog"
 	"fmt"
-	"sort"
 	"strconv"
 	"strings"
-
-	"github.com/aybabtme/rgbterm"
+	"time"
 )
 
+// LogLevel is ordered by severity, so that levels can be compared.
 type LogLevel int
 
 const (
 	// Special tokens
-	UNKNOWN = iota
-	CRITICAL
-	ERROR
-	WARNING
-	INFO
+	UNKNOWN LogLevel = iota
+	TRACE
 	DEBUG
+	INFO
+	NOTICE
+	WARNING
+	ERROR
+	CRITICAL
+	ALERT
+	FATAL
 )
 
+var levelNames = []string{
+	UNKNOWN:  "unknown",
+	TRACE:    "trace",
+	DEBUG:    "debug",
+	INFO:     "info",
+	NOTICE:   "notice",
+	WARNING:  "warning",
+	ERROR:    "error",
+	CRITICAL: "critical",
+	ALERT:    "alert",
+	FATAL:    "fatal",
+}
+
+func (l LogLevel) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("LogLevel(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
 type KeyValue struct {
 	Key   string
-	Value string
+	Value Value
 }
 
 type KeyValues []KeyValue
@@ -42,115 +66,160 @@ func (s KeyValues) Less(i, j int) bool {
 }
 
 type Log struct {
-	Level   LogLevel
-	Msg     string
-	Context KeyValues
+	Level LogLevel
+	// LevelName is the level as it was logged, e.g. "W", "warn" or "40"
+	LevelName string
+	// LevelInferred is set if the line did not declare a level, and Level
+	// was guessed from its message.
+	LevelInferred bool
+	Time          time.Time
+	Caller        string
+	Msg           string
+	Context       KeyValues
+}
+
+// syslogSeverityLevels maps syslog severities (0-7) onto log levels
+var syslogSeverityLevels = []LogLevel{
+	FATAL,    // emerg
+	ALERT,    // alert
+	CRITICAL, // crit
+	ERROR,    // err
+	WARNING,  // warning
+	NOTICE,   // notice
+	INFO,     // info
+	DEBUG,    // debug
+}
+
+// bunyanLevels maps bunyan/pino numeric levels (10-60) onto log levels
+var bunyanLevels = []LogLevel{
+	TRACE,   // 10
+	DEBUG,   // 20
+	INFO,    // 30
+	WARNING, // 40
+	ERROR,   // 50
+	FATAL,   // 60
+}
+
+// getLevelFromNumber interprets 0-7 as a syslog severity and 10-69 as a
+// bunyan level.
+func getLevelFromNumber(n int) LogLevel {
+	switch {
+	case n >= 0 && n < len(syslogSeverityLevels):
+		return syslogSeverityLevels[n]
+	case n >= 10 && n < 10*(len(bunyanLevels)+1):
+		return bunyanLevels[n/10-1]
+	default:
+		return UNKNOWN
+	}
 }
 
 func getLevelFromString(s string) LogLevel {
-	s = strings.ToLower(s)
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil {
+		return getLevelFromNumber(n)
+	}
 	switch s {
-	case "crit", "critical", "panic":
-		return CRITICAL
-	case "err", "error":
-		return ERROR
-	case "warn", "warning":
-		return WARNING
-	case "info":
-		return INFO
-	case "debug":
+	case "t", "trc", "trac", "trace":
+		return TRACE
+	case "d", "dbg", "debu", "debug":
 		return DEBUG
+	case "i", "inf", "info", "information", "informational":
+		return INFO
+	case "n", "notice":
+		return NOTICE
+	case "w", "wrn", "warn", "warning":
+		return WARNING
+	case "e", "err", "erro", "error":
+		return ERROR
+	case "c", "crt", "crit", "critical", "dpanic":
+		return CRITICAL
+	case "a", "alert":
+		return ALERT
+	case "f", "ftl", "fata", "fatal", "pani", "panic", "emerg", "emergency":
+		return FATAL
 	default:
 		return UNKNOWN
 	}
 }
 
+// ParseLogLevel parses a level name such as "warn" or "E", as given to
+// --min-level.
+func ParseLogLevel(s string) (LogLevel, error) {
+	level := getLevelFromString(s)
+	if level == UNKNOWN {
+		return UNKNOWN, fmt.Errorf("unknown level %q", s)
+	}
+	return level, nil
+}
+
+var levelLabels = map[LogLevel]string{
+	FATAL:    "FTL",
+	ALERT:    "ALR",
+	CRITICAL: "CRT",
+	ERROR:    "ERR",
+	WARNING:  "WRN",
+	NOTICE:   "NTC",
+	INFO:     "INF",
+	DEBUG:    "DBG",
+	TRACE:    "TRC",
+	UNKNOWN:  "UNK",
+}
+
 func LogLevelToColorString(l LogLevel) string {
-	switch l {
-	case CRITICAL:
-		return rgbterm.BgString("CRT", 255, 0, 0)
-	case ERROR:
-		return rgbterm.FgString("ERR", 255, 0, 0)
-	case WARNING:
-		return rgbterm.FgString("WRN", 255, 245, 32)
-	case INFO:
-		return rgbterm.FgString("INF", 20, 172, 190)
-	case DEBUG:
-		return rgbterm.FgString("DBG", 221, 28, 119)
-	case UNKNOWN:
-		return rgbterm.FgString("UNK", 221, 28, 119)
-	default:
+	label, ok := levelLabels[l]
+	if !ok {
 		panic(fmt.Sprintf("unhandled: %v", l))
 	}
+	return paint(theme.Levels[l], label)
 }
 
-func formatValue(value interface{}) string {
-	switch v := value.(type) {
-	case string:
-		return v
-	case int:
-		return strconv.Itoa(v)
-	case int64:
-		return strconv.FormatInt(v, 10)
-	case float64:
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	case []interface{}:
-		var buffer bytes.Buffer
-		buffer.WriteString("[")
-		for i, x := range v {
-			if i != 0 {
-				buffer.WriteString(" ")
-			}
-			buffer.WriteString(formatValue(x))
-		}
-		buffer.WriteString("]")
-		return buffer.String()
-	case map[interface{}]interface{}:
-		var buffer bytes.Buffer
-		buffer.WriteString("{")
-		first := true
-		for k, val := range v {
-			if first {
-				first = false
-			} else {
-				buffer.WriteString(" ")
-			}
-			buffer.WriteString(formatValue(k))
-			buffer.WriteString(":")
-			buffer.WriteString(formatValue(val))
-		}
-		buffer.WriteString("}")
-		return buffer.String()
-	default:
-		return fmt.Sprintf("%v", v)
+// InferredLevelToColorString renders a level which was inferred rather than
+// declared, using the lower case label in the level's color. Levels shown on
+// a background color use it as the foreground instead.
+func InferredLevelToColorString(l LogLevel) string {
+	label, ok := levelLabels[l]
+	if !ok {
+		panic(fmt.Sprintf("unhandled: %v", l))
 	}
+	style := theme.Levels[l]
+	fg := style.Fg
+	if style.Bg != nil {
+		fg = style.Bg
+	}
+	return paint(ansi.Style{Fg: fg}, strings.ToLower(label))
 }
 
 func parseJsonLog(l string) *Log {
-	parsedLog := map[string]interface{}{}
-	err := json.Unmarshal([]byte(l), &parsedLog)
-	if err != nil {
+	parsed, err := ParseJSONValue(l)
+	if err != nil || parsed.Kind != ObjectKind {
 		return nil
 	}
 	keyvalues := []KeyValue{}
 	msg := ""
-	level := LogLevel(UNKNOWN)
-	for k, v := range parsedLog {
-		switch k {
+	levelName := ""
+	var t time.Time
+	for _, kv := range parsed.Object {
+		switch kv.Key {
 		case "msg", "message":
-			msg = v.(string)
-		case "level":
-			level = getLevelFromString(v.(string))
-		case "time":
-			continue
+			msg = kv.Value.String()
+		case "level", "lvl", "severity":
+			levelName = kv.Value.String()
+		case "time", "ts", "@timestamp":
+			if logTime, ok := parseLogTime(kv.Value); ok && t.IsZero() {
+				t = logTime
+			} else {
+				keyvalues = append(keyvalues, kv)
+			}
 		default:
-			keyvalues = append(keyvalues, KeyValue{k, formatValue(v)})
+			keyvalues = append(keyvalues, kv)
 		}
 	}
 	return &Log{
-		Level:   level,
-		Msg:     msg,
-		Context: keyvalues,
+		Level:     getLevelFromString(levelName),
+		LevelName: levelName,
+		Time:      t,
+		Msg:       msg,
+		Context:   keyvalues,
 	}
 }
 
@@ -161,38 +230,114 @@ func parseKeyValueLog(l string) *Log {
 	}
 	keyValues := []KeyValue{}
 	msg := ""
-	level := LogLevel(UNKNOWN)
+	levelName := ""
+	var t time.Time
 	for _, kv := range parsedLog {
 		switch kv.Key {
 		case "msg", "message":
 			msg = kv.Value
-		case "level":
-			level = getLevelFromString(kv.Value)
-		case "time":
-			continue
+		case "level", "lvl", "severity":
+			levelName = kv.Value
+		case "time", "ts", "@timestamp":
+			if logTime, ok := parseLogTime(StringValue(kv.Value)); ok && t.IsZero() {
+				t = logTime
+			} else {
+				keyValues = append(keyValues, KeyValue{kv.Key, StringValue(kv.Value)})
+			}
 		default:
-			keyValues = append(keyValues, KeyValue{kv.Key, kv.Value})
+			keyValues = append(keyValues, KeyValue{kv.Key, StringValue(kv.Value)})
 		}
 	}
 	return &Log{
-		Level:   level,
-		Msg:     msg,
-		Context: keyValues,
+		Level:     getLevelFromString(levelName),
+		LevelName: levelName,
+		Time:      t,
+		Msg:       msg,
+		Context:   keyValues,
 	}
 }
 
-func ParseLog(l string) *Log {
-
-	log := parseJsonLog(l)
-	if log != nil {
-		return log
+func parseLayout(lay *layout.Layout, l string) *Log {
+	r, err := lay.Parse(l)
+	if err != nil {
+		return nil
+	}
+	keyValues := []KeyValue{}
+	if r.Logger != "" {
+		keyValues = append(keyValues, KeyValue{"logger", StringValue(r.Logger)})
+	}
+	for _, f := range r.Fields {
+		keyValues = append(keyValues, KeyValue{f.Key, StringValue(f.Value)})
 	}
+	return &Log{
+		Level:     getLevelFromString(r.Level),
+		LevelName: r.Level,
+		Time:      r.Time,
+		Caller:    r.Caller,
+		Msg:       r.Msg,
+		Context:   keyValues,
+	}
+}
 
-	log = parseKeyValueLog(l)
-	if log != nil {
-		return log
+func parseSyslogLog(l string) *Log {
+	m, err := syslog.Parse(l)
+	if err != nil {
+		return nil
+	}
+	keyValues := []KeyValue{
+		{"facility", StringValue(syslog.FacilityName(m.Facility))},
+	}
+	for _, kv := range []KeyValue{
+		{"host", StringValue(m.Hostname)},
+		{"app", StringValue(m.AppName)},
+		{"procid", StringValue(m.ProcID)},
+		{"msgid", StringValue(m.MsgID)},
+	} {
+		if kv.Value.Str != "" {
+			keyValues = append(keyValues, kv)
+		}
 	}
+	for _, e := range m.StructuredData {
+		for _, p := range e.Params {
+			keyValues = append(keyValues, KeyValue{e.ID + "." + p.Name, StringValue(p.Value)})
+		}
+	}
+	return &Log{
+		Level:     syslogSeverityLevels[m.Severity],
+		LevelName: syslog.SeverityName(m.Severity),
+		Time:      m.Timestamp,
+		Msg:       m.Msg,
+		Context:   keyValues,
+	}
+}
 
+// Source identifies where a log line came from.
+type Source struct {
+	Name  string
+	Image string
+	// Service is the compose or swarm service the container belongs to, or
+	// its name for standalone containers; Replica is its number within the
+	// service, starting at 1, or 0 if unknown.
+	Service string
+	Replica int
+	// Project is the compose project of the container, if known.
+	Project string
+}
+
+// ParseSourceLog parses a line by trying the user defined parse rules which
+// apply to the source, followed by the builtin formats.
+func ParseSourceLog(src Source, l string) *Log {
+	log, _ := parseFirst(formatsFor(src), l)
+	return log
+}
+
+// ParseLog parses a line of an unknown source, trying the user defined parse
+// rules which apply to all sources before the builtin formats.
+func ParseLog(l string) *Log {
+	return ParseSourceLog(Source{}, l)
+}
+
+func parseRawLog(l string) *Log {
 	keyValues := []KeyValue{}
 	return &Log{
 		Level:   UNKNOWN,
@@ -201,16 +346,7 @@ func ParseLog(l string) *Log {
 	}
 }
 
+// Format renders the log using the DefaultFormatter.
 func (l *Log) Format() string {
-
-	buf := []string{}
-	buf = append(buf, LogLevelToColorString(l.Level))
-	if l.Msg != "" {
-		buf = append(buf, rgbterm.FgString(l.Msg, 255, 255, 255))
-	}
-	sort.Sort(l.Context)
-	for _, x := range l.Context {
-		buf = append(buf, rgbterm.FgString(x.Key, 0, 100, 90)+rgbterm.FgString("=", 190, 190, 190)+rgbterm.FgString(x.Value, 120, 120, 120))
-	}
-	return strings.Join(buf, " ")
+	return DefaultFormatter.Format(l)
 }
@@ -0,0 +1,89 @@
+package dockerlogs
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure level names, abbreviations and numeric levels are understood.
+func TestGetLevelFromString(t *testing.T) {
+	var tests = []struct {
+		s     string
+		level LogLevel
+	}{
+		{s: "TRACE", level: TRACE},
+		{s: "D", level: DEBUG},
+		{s: "I", level: INFO},
+		{s: "notice", level: NOTICE},
+		{s: "WARN", level: WARNING},
+		{s: "w", level: WARNING},
+		{s: "ERRO", level: ERROR},
+		{s: "E", level: ERROR},
+		{s: "crit", level: CRITICAL},
+		{s: "alert", level: ALERT},
+		{s: "emerg", level: FATAL},
+		{s: "panic", level: FATAL},
+		{s: "3", level: ERROR},
+		{s: "0", level: FATAL},
+		{s: "30", level: INFO},
+		{s: "50", level: ERROR},
+		{s: "60", level: FATAL},
+		{s: "9", level: UNKNOWN},
+		{s: "", level: UNKNOWN},
+		{s: "loud", level: UNKNOWN},
+	}
+	for i, tt := range tests {
+		if level := getLevelFromString(tt.s); level != tt.level {
+			t.Errorf("%d. %q: exp=%s got=%s", i, tt.s, tt.level, level)
+		}
+	}
+}
+
+// Ensure levels are ordered by severity and the logged level is kept.
+func TestParseLog_Level(t *testing.T) {
+	if !(TRACE < DEBUG && DEBUG < INFO && INFO < NOTICE && NOTICE < WARNING &&
+		WARNING < ERROR && ERROR < CRITICAL && CRITICAL < ALERT && ALERT < FATAL) {
+		t.Fatalf("levels are not ordered by severity")
+	}
+
+	log := ParseLog(`{"level":40,"msg":"slow"}`)
+	if log.Level != WARNING || log.LevelName != "40" {
+		t.Errorf("expected warning from bunyan level, got %s (%q)", log.Level, log.LevelName)
+	}
+	log = ParseLog(`W0102 15:04:05.123456    1 file.go:42] hi`)
+	if log.Level != WARNING || log.LevelName != "W" {
+		t.Errorf("expected warning from glog level, got %s (%q)", log.Level, log.LevelName)
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Errorf("expected error for unknown level")
+	}
+}
+
+// Ensure lines with an invalid syslog PRI are kept as plain text.
+func TestParseLog_InvalidSyslog(t *testing.T) {
+	for _, line := range []string{`<-1>1 - - - - - -`, `<-3>Oct 11 22:14:15 host x: y`, `<192>1 - - - - - -`} {
+		if log := ParseLog(line); log.Msg != line {
+			t.Errorf("%q: expected the line as message, got %q", line, log.Msg)
+		}
+	}
+}
+
+// Ensure numbers which are not plausible unix times are kept as fields.
+func TestParseLog_Time(t *testing.T) {
+	log := ParseLog(`{"msg":"tick","ts":42}`)
+	if !log.Time.IsZero() || len(log.Context) != 1 || log.Context[0].Value.String() != "42" {
+		t.Errorf("expected ts to be kept as a field, got %v and %q", log.Time, keys(log.Context))
+	}
+	log = ParseLog(`{"msg":"x","time":42}`)
+	if !log.Time.IsZero() || len(log.Context) != 1 || log.Context[0].Key != "time" {
+		t.Errorf("expected time to be kept as a field, got %v and %q", log.Time, keys(log.Context))
+	}
+	log = ParseLog(`msg=x time=soon`)
+	if !log.Time.IsZero() || len(log.Context) != 1 || log.Context[0].Key != "time" {
+		t.Errorf("expected time to be kept as a field, got %v and %q", log.Time, keys(log.Context))
+	}
+	log = ParseLog(`msg=tick ts=1483267320`)
+	if exp := time.Unix(1483267320, 0); !log.Time.Equal(exp) || len(log.Context) != 0 {
+		t.Errorf("expected the time %v, got %v and %q", exp, log.Time, keys(log.Context))
+	}
+}
@@ -0,0 +1,134 @@
+package dockerlogs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Stream names, as found in Record.Stream
+const (
+	Stdin  = "stdin"
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+var streamNames = []string{Stdin, Stdout, Stderr}
+
+type streamLine struct {
+	Stream string
+	Line   string
+}
+
+// logReader splits the body of the container logs endpoint into lines.
+//
+// Unless the container has a tty, stdout and stderr are multiplexed into
+// frames, each of which starts with an 8 byte header: the stream (0, 1 or 2),
+// three zero bytes and the big endian payload size. Long lines are split over
+// several frames, in which case every frame repeats the timestamp prefix.
+type logReader struct {
+	r           *bufio.Reader
+	multiplexed bool
+	detected    bool
+	pending     [3][]byte
+	lines       []streamLine
+}
+
+func newLogReader(r io.Reader) *logReader {
+	return &logReader{r: bufio.NewReader(r)}
+}
+
+// ReadLine returns the next line without its trailing newline.
+func (lr *logReader) ReadLine() (stream, line string, err error) {
+	if !lr.detected {
+		lr.detected = true
+		header, _ := lr.r.Peek(8)
+		lr.multiplexed = len(header) == 8 && header[0] <= 2 &&
+			header[1] == 0 && header[2] == 0 && header[3] == 0
+	}
+
+	if !lr.multiplexed {
+		line, err := lr.r.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return Stdout, trimNewline(line), err
+	}
+
+	for len(lr.lines) == 0 {
+		if err := lr.readFrame(); err != nil {
+			if err == io.EOF {
+				lr.flush()
+				if len(lr.lines) > 0 {
+					break
+				}
+			}
+			return "", "", err
+		}
+	}
+	l := lr.lines[0]
+	lr.lines = lr.lines[1:]
+	return l.Stream, l.Line, nil
+}
+
+func (lr *logReader) readFrame() error {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(lr.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+	stream := int(header[0])
+	if stream > 2 {
+		stream = 1
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+	if _, err := io.ReadFull(lr.r, payload); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+
+	pending := lr.pending[stream]
+	if len(pending) > 0 {
+		// continuation of a long line, drop the repeated timestamp
+		if i := bytes.IndexByte(payload, ' '); i > 0 {
+			if _, err := time.Parse(time.RFC3339Nano, string(payload[:i])); err == nil {
+				payload = payload[i+1:]
+			}
+		}
+	}
+	pending = append(pending, payload...)
+
+	for {
+		i := bytes.IndexByte(pending, '\n')
+		if i == -1 {
+			break
+		}
+		lr.lines = append(lr.lines, streamLine{streamNames[stream], trimNewline(string(pending[:i+1]))})
+		pending = pending[i+1:]
+	}
+	lr.pending[stream] = append([]byte{}, pending...)
+	return nil
+}
+
+// flush emits any partial lines which were not terminated by a newline.
+func (lr *logReader) flush() {
+	for stream, pending := range lr.pending {
+		if len(pending) > 0 {
+			lr.lines = append(lr.lines, streamLine{streamNames[stream], string(pending)})
+			lr.pending[stream] = nil
+		}
+	}
+}
+
+func trimNewline(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package dockerlogs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func readLines(t *testing.T, body []byte) []streamLine {
+	lr := newLogReader(bytes.NewReader(body))
+	lines := []streamLine{}
+	for {
+		stream, line, err := lr.ReadLine()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, streamLine{stream, line})
+	}
+}
+
+// Ensure multiplexed frames are split into lines of the right stream, and
+// lines split over several frames are joined.
+func TestLogReader_Multiplexed(t *testing.T) {
+	body := []byte{}
+	body = append(body, frame(1, "2017-01-01T10:00:00.000000001Z hello\n")...)
+	body = append(body, frame(2, "2017-01-01T10:00:00.000000002Z a long ")...)
+	body = append(body, frame(1, "2017-01-01T10:00:00.000000003Z world\n")...)
+	body = append(body, frame(2, "2017-01-01T10:00:00.000000002Z line\n")...)
+	body = append(body, frame(2, "2017-01-01T10:00:00.000000004Z no newline")...)
+
+	exp := []streamLine{
+		{Stdout, "2017-01-01T10:00:00.000000001Z hello"},
+		{Stdout, "2017-01-01T10:00:00.000000003Z world"},
+		{Stderr, "2017-01-01T10:00:00.000000002Z a long line"},
+		{Stderr, "2017-01-01T10:00:00.000000004Z no newline"},
+	}
+	if got := readLines(t, body); !reflect.DeepEqual(exp, got) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
+
+// Ensure the logs of containers with a tty are read as plain lines.
+func TestLogReader_TTY(t *testing.T) {
+	body := []byte("2017-01-01T10:00:00Z hello\n2017-01-01T10:00:01Z world")
+	exp := []streamLine{
+		{Stdout, "2017-01-01T10:00:00Z hello"},
+		{Stdout, "2017-01-01T10:00:01Z world"},
+	}
+	if got := readLines(t, body); !reflect.DeepEqual(exp, got) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
@@ -118,7 +118,6 @@ func (s *Scanner) scanQuotedString() (tok Token, lit string) {
 			_, _ = buf.WriteRune(ch)
 		}
 	}
-	panic("should never reach here")
 }
 
 // read reads the next rune from the bufferred reader.
@@ -37,7 +37,7 @@ func TestScanner_Scan(t *testing.T) {
 		s := keyvalue.NewScanner(strings.NewReader(tt.s))
 		tok, lit := s.Scan()
 		if tt.tok != tok {
-			t.Errorf("%d. %q token mismatch: exp=%q got=%q <%q>", i, tt.s, tt.tok, tok, lit)
+			t.Errorf("%d. %q token mismatch: exp=%d got=%d <%q>", i, tt.s, tt.tok, tok, lit)
 		} else if tt.lit != lit {
 			t.Errorf("%d. %q literal mismatch: exp=%q got=%q", i, tt.s, tt.lit, lit)
 		}
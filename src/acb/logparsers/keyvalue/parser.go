@@ -46,7 +46,7 @@ func (p *Parser) Parse() ([]KeyValuePair, error) {
 		}
 
 		tok, lit = p.scanIgnoreWhitespace()
-		if tok == IDENT && tok == STRING {
+		if tok == ILLEGAL {
 			return nil, fmt.Errorf("found %q, expected ident or string", lit)
 		}
 		value := lit
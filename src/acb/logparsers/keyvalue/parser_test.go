@@ -34,6 +34,14 @@ func TestParser_ParseStatement(t *testing.T) {
 				{Key: "key2", Value: "val2=val"},
 			},
 		},
+		{
+			s:   `key="value`,
+			err: `found "value", expected ident or string`,
+		},
+		{
+			s:   "level=\x1b[33mwarn",
+			err: `found "\x1b", expected ident or string`,
+		},
 	}
 
 	for i, tt := range tests {
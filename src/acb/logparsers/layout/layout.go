@@ -0,0 +1,195 @@
+// Package layout parses log lines written using the default layouts of
+// common logging libraries (glog/klog, python logging, log4j).
+//
+// A layout is a regular expression with named capture groups. The groups
+// "level", "time", "caller", "logger" and "msg" are given special meaning,
+// any other named group is returned as an additional field.
+package layout
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Field represents a named capture group which has no special meaning.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Record represents a log line which was matched by a layout.
+type Record struct {
+	Level  string
+	Time   time.Time
+	Caller string
+	Logger string
+	Msg    string
+	Fields []Field
+}
+
+// Layout represents a regular expression based log line parser.
+type Layout struct {
+	Name   string
+	Regexp *regexp.Regexp
+
+	// TimeLayout is the time.Parse layout used for the "time" group.
+	// If the layout does not contain a year, it is set by InferYear.
+	TimeLayout string
+
+	// Levels maps the (case sensitive) contents of the "level" group onto
+	// a level name, e.g. "W" -> "warning". Unmapped levels are returned as is.
+	Levels map[string]string
+
+	// Groups renames capture groups before they are interpreted, e.g.
+	// "severity" -> "level" or "uid" -> "user_id".
+	Groups map[string]string
+}
+
+// New returns a new layout for the given regular expression.
+func New(name, expr, timeLayout string) (*Layout, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("layout %s: %v", name, err)
+	}
+	return &Layout{
+		Name:       name,
+		Regexp:     re,
+		TimeLayout: timeLayout,
+	}, nil
+}
+
+// MustNew is like New but panics if the expression cannot be compiled.
+func MustNew(name, expr, timeLayout string) *Layout {
+	l, err := New(name, expr, timeLayout)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Parse matches the line against the layout. Optional groups which take no
+// part in the match are left out.
+func (l *Layout) Parse(line string) (*Record, error) {
+	m := l.Regexp.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, fmt.Errorf("line does not match %s layout", l.Name)
+	}
+
+	r := &Record{}
+	for i, name := range l.Regexp.SubexpNames() {
+		if i == 0 || name == "" || m[2*i] == -1 {
+			continue
+		}
+		if mapped, ok := l.Groups[name]; ok {
+			name = mapped
+		}
+		v := line[m[2*i]:m[2*i+1]]
+		switch name {
+		case "level":
+			if mapped, ok := l.Levels[v]; ok {
+				v = mapped
+			}
+			r.Level = v
+		case "time":
+			t, err := l.parseTime(v)
+			if err != nil {
+				return nil, err
+			}
+			r.Time = t
+		case "caller":
+			r.Caller = v
+		case "logger":
+			r.Logger = v
+		case "msg", "message":
+			r.Msg = v
+		default:
+			r.Fields = append(r.Fields, Field{name, v})
+		}
+	}
+	return r, nil
+}
+
+func (l *Layout) parseTime(s string) (time.Time, error) {
+	if s == "" || l.TimeLayout == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(l.TimeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("layout %s: %v", l.Name, err)
+	}
+	if t.Year() == 0 {
+		t = InferYear(t, time.Now())
+	}
+	return t, nil
+}
+
+// InferYear sets the year of a time parsed without one to the latest year in
+// which it is not more than a day after now, so that lines logged on Dec 31
+// and read on Jan 1 belong to the previous year. Feb 29 goes back to the last
+// leap year.
+func InferYear(t, now time.Time) time.Time {
+	for y := now.Year(); y > now.Year()-8; y-- {
+		d := time.Date(y, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if d.Month() == t.Month() && d.Sub(now) <= 24*time.Hour {
+			return d
+		}
+	}
+	return t
+}
+
+// Glog matches the glog/klog header, e.g.
+// I0102 15:04:05.123456    1 file.go:42] msg
+var Glog = &Layout{
+	Name: "glog",
+	Regexp: regexp.MustCompile(
+		`^(?P<level>[IWEF])(?P<time>\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+(?P<thread>\d+) (?P<caller>[^ \]]+:\d+)\] (?P<msg>.*)$`),
+	TimeLayout: "0102 15:04:05.000000",
+}
+
+// Python matches the python logging BASIC_FORMAT, e.g.
+// WARNING:root:msg
+var Python = &Layout{
+	Name: "python",
+	Regexp: regexp.MustCompile(
+		`^(?P<level>DEBUG|INFO|WARNING|ERROR|CRITICAL):(?P<logger>[^:]*):(?P<msg>.*)$`),
+}
+
+// Log4j matches the log4j/logback default console layout, e.g.
+// 2024-01-02 10:00:00,123 ERROR [main] c.e.Foo - msg
+var Log4j = &Layout{
+	Name: "log4j",
+	Regexp: regexp.MustCompile(
+		`^(?P<time>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}[,.]\d{3})\s+(?P<level>TRACE|DEBUG|INFO|WARN|ERROR|FATAL)\s+\[(?P<thread>[^\]]*)\]\s+(?P<logger>\S+)\s+-\s?(?P<msg>.*)$`),
+	TimeLayout: "2006-01-02 15:04:05.000",
+}
+
+// CLF matches the NCSA common and combined log formats used by web servers
+// such as nginx and apache, e.g.
+// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "-" "curl/7.0"
+var CLF = &Layout{
+	Name: "clf",
+	Regexp: regexp.MustCompile(
+		`^(?P<remote_addr>\S+) \S+ (?P<user>\S+) \[(?P<time>[^\]]+)\] "(?P<msg>(?P<method>[A-Z]+) (?P<path>\S+) (?P<protocol>[^"]*))" (?P<status>\d{3}) (?P<size>\d+|-)(?: "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)")?$`),
+	TimeLayout: "02/Jan/2006:15:04:05 -0700",
+}
+
+// Builtin lists the layouts which are tried when parsing a line of an
+// unknown format.
+var Builtin = []*Layout{
+	Glog,
+	Python,
+	Log4j,
+	CLF,
+}
+
+// Lookup returns the builtin layout with the given name, or nil.
+func Lookup(name string) *Layout {
+	for _, l := range Builtin {
+		if strings.EqualFold(l.Name, name) {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package layout_test
+
+import (
+	"acb/logparsers/layout"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Ensure the builtin layouts extract level, time, caller/logger and message.
+func TestLayout_Parse(t *testing.T) {
+	// times without a year are in the current year, or the last if that
+	// would put them in the future
+	now := time.Now()
+	date := func(month time.Month, day, hour, min, sec, nsec int) time.Time {
+		t := time.Date(now.Year(), month, day, hour, min, sec, nsec, time.UTC)
+		if t.Sub(now) > 24*time.Hour {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t
+	}
+	var tests = []struct {
+		layout *layout.Layout
+		s      string
+		record *layout.Record
+	}{
+		{
+			layout: layout.Glog,
+			s:      `I0102 15:04:05.123456    1 file.go:42] hello world`,
+			record: &layout.Record{
+				Level:  "I",
+				Time:   date(1, 2, 15, 4, 5, 123456000),
+				Caller: "file.go:42",
+				Msg:    "hello world",
+				Fields: []layout.Field{{Key: "thread", Value: "1"}},
+			},
+		},
+		{
+			layout: layout.Glog,
+			s:      `E1231 23:59:59.000001 4242 pkg/server.go:7] failed: boom`,
+			record: &layout.Record{
+				Level:  "E",
+				Time:   date(12, 31, 23, 59, 59, 1000),
+				Caller: "pkg/server.go:7",
+				Msg:    "failed: boom",
+				Fields: []layout.Field{{Key: "thread", Value: "4242"}},
+			},
+		},
+		{
+			layout: layout.Python,
+			s:      `WARNING:root:disk almost full`,
+			record: &layout.Record{
+				Level:  "WARNING",
+				Logger: "root",
+				Msg:    "disk almost full",
+			},
+		},
+		{
+			layout: layout.Log4j,
+			s:      `2024-01-02 10:00:00,123 ERROR [main] c.e.Foo - it broke`,
+			record: &layout.Record{
+				Level:  "ERROR",
+				Time:   time.Date(2024, 1, 2, 10, 0, 0, 123000000, time.UTC),
+				Logger: "c.e.Foo",
+				Msg:    "it broke",
+				Fields: []layout.Field{{Key: "thread", Value: "main"}},
+			},
+		},
+		{
+			layout: layout.CLF,
+			s:      `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "-" "curl/7.0"`,
+			record: &layout.Record{
+				Time: time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+				Msg:  "GET /a.gif HTTP/1.0",
+				Fields: []layout.Field{
+					{Key: "remote_addr", Value: "127.0.0.1"},
+					{Key: "user", Value: "frank"},
+					{Key: "method", Value: "GET"},
+					{Key: "path", Value: "/a.gif"},
+					{Key: "protocol", Value: "HTTP/1.0"},
+					{Key: "status", Value: "200"},
+					{Key: "size", Value: "2326"},
+					{Key: "referer", Value: "-"},
+					{Key: "user_agent", Value: "curl/7.0"},
+				},
+			},
+		},
+		{
+			layout: layout.CLF,
+			s:      `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 404 -`,
+			record: &layout.Record{
+				Time: time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+				Msg:  "GET /a.gif HTTP/1.0",
+				Fields: []layout.Field{
+					{Key: "remote_addr", Value: "127.0.0.1"},
+					{Key: "user", Value: "-"},
+					{Key: "method", Value: "GET"},
+					{Key: "path", Value: "/a.gif"},
+					{Key: "protocol", Value: "HTTP/1.0"},
+					{Key: "status", Value: "404"},
+					{Key: "size", Value: "-"},
+				},
+			},
+		},
+		{layout: layout.Glog, s: `key=value`},
+		{layout: layout.Python, s: `2024-01-02 10:00:00,123 ERROR [main] c.e.Foo - it broke`},
+		{layout: layout.Log4j, s: `WARNING:root:disk almost full`},
+	}
+
+	for i, tt := range tests {
+		record, err := tt.layout.Parse(tt.s)
+		if tt.record == nil {
+			if err == nil {
+				t.Errorf("%d. %q: expected %s layout not to match", i, tt.s, tt.layout.Name)
+			}
+		} else if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if !reflect.DeepEqual(tt.record, record) {
+			t.Errorf("%d. %q\n\nrecord mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.record, record)
+		}
+	}
+}
+
+// Ensure user supplied expressions are compiled and their extra groups kept.
+func TestNew(t *testing.T) {
+	l, err := layout.New("custom", `^\[(?P<level>\w+)\] (?P<user>\w+): (?P<msg>.*)$`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := l.Parse(`[warn] bob: hi`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &layout.Record{Level: "warn", Msg: "hi", Fields: []layout.Field{{Key: "user", Value: "bob"}}}
+	if !reflect.DeepEqual(exp, record) {
+		t.Errorf("record mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", exp, record)
+	}
+
+	l.Groups = map[string]string{"user": "user_id"}
+	record, err = l.Parse(`[warn] bob: hi`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp.Fields = []layout.Field{{Key: "user_id", Value: "bob"}}
+	if !reflect.DeepEqual(exp, record) {
+		t.Errorf("renamed record mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", exp, record)
+	}
+
+	if _, err := layout.New("bad", `(`, ""); err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+}
+
+// Ensure times without a year are not placed in the future.
+func TestInferYear(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 30, 0, 0, time.UTC)
+	var tests = []struct {
+		t   time.Time
+		exp time.Time
+	}{
+		{t: time.Date(0, 1, 1, 0, 10, 0, 0, time.UTC), exp: time.Date(2017, 1, 1, 0, 10, 0, 0, time.UTC)},
+		{t: time.Date(0, 12, 31, 23, 59, 0, 0, time.UTC), exp: time.Date(2016, 12, 31, 23, 59, 0, 0, time.UTC)},
+		{t: time.Date(0, 1, 1, 23, 0, 0, 0, time.UTC), exp: time.Date(2017, 1, 1, 23, 0, 0, 0, time.UTC)},
+		{t: time.Date(0, 2, 29, 12, 0, 0, 0, time.UTC), exp: time.Date(2016, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+	for i, tt := range tests {
+		if got := layout.InferYear(tt.t, now); !got.Equal(tt.exp) {
+			t.Errorf("%d. exp=%v got=%v", i, tt.exp, got)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package syslog parses RFC5424 and RFC3164 (BSD) formatted syslog lines.
+package syslog
+
+import (
+	"acb/logparsers/layout"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Param represents a single SD-PARAM of a structured data element.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Element represents a single SD-ELEMENT, e.g. [exampleSDID@32473 iut="3"]
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+// Message represents a parsed syslog line. Fields which are not present
+// (or given as the NILVALUE "-") are left empty.
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int // 0 for RFC3164 messages
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData []Element
+	Msg            string
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// SeverityName returns the keyword of the severity, e.g. 3 -> "err".
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(severityNames) {
+		return strconv.Itoa(severity)
+	}
+	return severityNames[severity]
+}
+
+// FacilityName returns the keyword of the facility, e.g. 16 -> "local0".
+func FacilityName(facility int) string {
+	if facility < 0 || facility >= len(facilityNames) {
+		return strconv.Itoa(facility)
+	}
+	return facilityNames[facility]
+}
+
+// Parse parses an RFC5424 line, falling back to RFC3164 if no version is
+// present after the PRI.
+func Parse(line string) (*Message, error) {
+	p := &parser{s: line}
+	m := &Message{}
+
+	pri, err := p.pri()
+	if err != nil {
+		return nil, err
+	}
+	m.Facility = pri / 8
+	m.Severity = pri % 8
+
+	if version, ok := p.version(); ok {
+		m.Version = version
+		err = p.rfc5424(m)
+	} else {
+		err = p.rfc3164(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parser holds the remaining unparsed input.
+type parser struct {
+	s string
+}
+
+// pri parses the <PRI> header.
+func (p *parser) pri() (int, error) {
+	if !strings.HasPrefix(p.s, "<") {
+		return 0, fmt.Errorf("expected <PRI>")
+	}
+	end := strings.IndexByte(p.s, '>')
+	if end < 2 || end > 4 {
+		return 0, fmt.Errorf("expected <PRI>")
+	}
+	pri := 0
+	for i := 1; i < end; i++ {
+		if !isDigit(p.s[i]) {
+			return 0, fmt.Errorf("invalid PRI %q", p.s[1:end])
+		}
+		pri = pri*10 + int(p.s[i]-'0')
+	}
+	if pri > 191 {
+		return 0, fmt.Errorf("invalid PRI %q", p.s[1:end])
+	}
+	p.s = p.s[end+1:]
+	return pri, nil
+}
+
+// version parses the VERSION which directly follows the PRI in RFC5424.
+func (p *parser) version() (int, bool) {
+	i := 0
+	for i < len(p.s) && i < 3 && isDigit(p.s[i]) {
+		i++
+	}
+	if i == 0 || i >= len(p.s) || p.s[i] != ' ' || p.s[0] == '0' {
+		return 0, false
+	}
+	version, _ := strconv.Atoi(p.s[:i])
+	p.s = p.s[i+1:]
+	return version, true
+}
+
+// field consumes the next space delimited header field; "-" yields "".
+func (p *parser) field() (string, error) {
+	if p.s == "" {
+		return "", fmt.Errorf("unexpected end of header")
+	}
+	end := strings.IndexByte(p.s, ' ')
+	var f string
+	if end == -1 {
+		f, p.s = p.s, ""
+	} else {
+		f, p.s = p.s[:end], p.s[end+1:]
+	}
+	if f == "-" {
+		return "", nil
+	}
+	return f, nil
+}
+
+func (p *parser) rfc5424(m *Message) error {
+	ts, err := p.field()
+	if err != nil {
+		return err
+	}
+	if ts != "" {
+		m.Timestamp, err = time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+	for _, dst := range []*string{&m.Hostname, &m.AppName, &m.ProcID, &m.MsgID} {
+		if *dst, err = p.field(); err != nil {
+			return err
+		}
+	}
+
+	m.StructuredData, err = p.structuredData()
+	if err != nil {
+		return err
+	}
+	p.s = strings.TrimPrefix(p.s, " ")
+	m.Msg = strings.TrimPrefix(p.s, "\ufeff")
+	return nil
+}
+
+// structuredData parses either the NILVALUE or one or more SD-ELEMENTs.
+func (p *parser) structuredData() ([]Element, error) {
+	if p.s == "-" || strings.HasPrefix(p.s, "- ") {
+		p.s = p.s[1:]
+		return nil, nil
+	}
+	if !strings.HasPrefix(p.s, "[") {
+		return nil, fmt.Errorf("expected structured data")
+	}
+
+	elements := []Element{}
+	for strings.HasPrefix(p.s, "[") {
+		p.s = p.s[1:]
+		end := strings.IndexAny(p.s, " ]")
+		if end <= 0 {
+			return nil, fmt.Errorf("expected SD-ID")
+		}
+		e := Element{ID: p.s[:end]}
+		p.s = p.s[end:]
+
+		for {
+			p.s = strings.TrimLeft(p.s, " ")
+			if strings.HasPrefix(p.s, "]") {
+				p.s = p.s[1:]
+				break
+			}
+			param, err := p.param()
+			if err != nil {
+				return nil, err
+			}
+			e.Params = append(e.Params, param)
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
+
+// param parses a single name="value" pair, handling \" \\ and \] escapes.
+func (p *parser) param() (Param, error) {
+	eq := strings.Index(p.s, `="`)
+	if eq <= 0 {
+		return Param{}, fmt.Errorf("expected SD-PARAM")
+	}
+	name := p.s[:eq]
+	p.s = p.s[eq+2:]
+
+	var buf strings.Builder
+	for i := 0; i < len(p.s); i++ {
+		switch c := p.s[i]; c {
+		case '\\':
+			if i+1 < len(p.s) && (p.s[i+1] == '"' || p.s[i+1] == '\\' || p.s[i+1] == ']') {
+				i++
+				c = p.s[i]
+			}
+			buf.WriteByte(c)
+		case '"':
+			p.s = p.s[i+1:]
+			return Param{name, buf.String()}, nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return Param{}, fmt.Errorf("unterminated SD-PARAM value")
+}
+
+const rfc3164Stamp = "Jan _2 15:04:05"
+
+func (p *parser) rfc3164(m *Message) error {
+	if len(p.s) < len(rfc3164Stamp) {
+		return fmt.Errorf("expected timestamp")
+	}
+	ts, err := time.Parse(rfc3164Stamp, p.s[:len(rfc3164Stamp)])
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", p.s[:len(rfc3164Stamp)])
+	}
+	m.Timestamp = layout.InferYear(ts, time.Now())
+	p.s = strings.TrimPrefix(p.s[len(rfc3164Stamp):], " ")
+
+	// local senders leave the hostname out, e.g. myapp[123]: msg
+	first := p.s
+	if end := strings.IndexByte(p.s, ' '); end != -1 {
+		first = p.s[:end]
+	}
+	if !strings.HasSuffix(first, ":") && !strings.Contains(first, "[") {
+		if m.Hostname, err = p.field(); err != nil {
+			return err
+		}
+	}
+
+	// TAG[PID]: MSG, the tag is optional
+	end := strings.IndexAny(p.s, ":[ ")
+	if end > 0 && (p.s[end] == ':' || p.s[end] == '[') {
+		m.AppName = p.s[:end]
+		p.s = p.s[end:]
+		if strings.HasPrefix(p.s, "[") {
+			pid := strings.IndexByte(p.s, ']')
+			if pid == -1 {
+				return fmt.Errorf("unterminated PID")
+			}
+			m.ProcID = p.s[1:pid]
+			p.s = p.s[pid+1:]
+		}
+		p.s = strings.TrimPrefix(p.s, ":")
+		p.s = strings.TrimPrefix(p.s, " ")
+	}
+	m.Msg = p.s
+	return nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,127 @@
+package syslog_test
+
+import (
+	"acb/logparsers/layout"
+	"acb/logparsers/syslog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Ensure the parser can parse RFC5424 and RFC3164 lines.
+func TestParse(t *testing.T) {
+	// RFC3164 timestamps have no year, see layout.InferYear
+	now := time.Now()
+	var tests = []struct {
+		s   string
+		msg *syslog.Message
+		err string
+	}{
+		{
+			s: `<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8`,
+			msg: &syslog.Message{
+				Facility:  4,
+				Severity:  2,
+				Version:   1,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "su",
+				MsgID:     "ID47",
+				Msg:       "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			s: `<165>1 2003-10-11T22:14:15.003Z host app 123 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][examplePriority@32473 class="high \"q\" \]"] msg`,
+			msg: &syslog.Message{
+				Facility:  20,
+				Severity:  5,
+				Version:   1,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "host",
+				AppName:   "app",
+				ProcID:    "123",
+				MsgID:     "ID47",
+				StructuredData: []syslog.Element{
+					{ID: "exampleSDID@32473", Params: []syslog.Param{{"iut", "3"}, {"eventSource", "Application"}}},
+					{ID: "examplePriority@32473", Params: []syslog.Param{{"class", `high "q" ]`}}},
+				},
+				Msg: "msg",
+			},
+		},
+		{
+			s: `<13>1 - - - - - -`,
+			msg: &syslog.Message{
+				Facility: 1,
+				Severity: 5,
+				Version:  1,
+			},
+		},
+		{
+			s: `<34>Oct 11 22:14:15 mymachine su[99]: 'su root' failed`,
+			msg: &syslog.Message{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: layout.InferYear(time.Date(0, 10, 11, 22, 14, 15, 0, time.UTC), now),
+				Hostname:  "mymachine",
+				AppName:   "su",
+				ProcID:    "99",
+				Msg:       "'su root' failed",
+			},
+		},
+		{
+			s: `<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!`,
+			msg: &syslog.Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: layout.InferYear(time.Date(0, 2, 5, 17, 32, 18, 0, time.UTC), now),
+				Hostname:  "10.0.0.99",
+				Msg:       "Use the BFG!",
+			},
+		},
+		{
+			s: `<13>Feb  5 17:32:18 myapp[123]: hello`,
+			msg: &syslog.Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: layout.InferYear(time.Date(0, 2, 5, 17, 32, 18, 0, time.UTC), now),
+				AppName:   "myapp",
+				ProcID:    "123",
+				Msg:       "hello",
+			},
+		},
+		{
+			s: `<13>Feb  5 17:32:18 cron: job done`,
+			msg: &syslog.Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: layout.InferYear(time.Date(0, 2, 5, 17, 32, 18, 0, time.UTC), now),
+				AppName:   "cron",
+				Msg:       "job done",
+			},
+		},
+		{s: `key=value`, err: `expected <PRI>`},
+		{s: `<999>1 - - - - - -`, err: `invalid PRI "999"`},
+		{s: `<-1>1 - - - - - -`, err: `invalid PRI "-1"`},
+		{s: `<+5>1 - - - - - -`, err: `invalid PRI "+5"`},
+		{s: `<-3>Oct 11 22:14:15 host x: y`, err: `invalid PRI "-3"`},
+		{s: `<34>1 yesterday host app - - - msg`, err: `invalid timestamp "yesterday"`},
+		{s: `<34>1 - host app - - [id a="b`, err: `unterminated SD-PARAM value`},
+	}
+
+	for i, tt := range tests {
+		msg, err := syslog.Parse(tt.s)
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && !reflect.DeepEqual(tt.msg, msg) {
+			t.Errorf("%d. %q\n\nmessage mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.msg, msg)
+		}
+	}
+}
+
+// errstring returns the string representation of an error.
+func errstring(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"strings"
+)
+
+// NameColumn lays out the container name column of the text output. Its
+// width fits the names of the containers being tailed, which are added and
+// removed as their logs start and end.
+type NameColumn struct {
+	// MaxWidth limits the width of the column, longer names are truncated.
+	// 0 means no limit.
+	MaxWidth int
+	// Abbreviate drops the compose project from names such as
+	// project_web_1.
+	Abbreviate bool
+
+	widths map[string]int
+	width  int
+}
+
+// Name returns the name shown for the source, which is at most MaxWidth
+// wide.
+func (c *NameColumn) Name(src Source) string {
+	name := src.Name
+	if c.Abbreviate && src.Project != "" {
+		for _, sep := range []string{"_", "-"} {
+			if short := strings.TrimPrefix(name, src.Project+sep); short != name && short != "" {
+				name = short
+				break
+			}
+		}
+	}
+	if c.MaxWidth > 0 {
+		name = ansi.Truncate(name, c.MaxWidth, "…")
+	}
+	return name
+}
+
+// Join adds a container whose logs are being tailed.
+func (c *NameColumn) Join(src Source) {
+	if c.widths == nil {
+		c.widths = map[string]int{}
+	}
+	c.widths[src.Name] = ansi.Width(c.Name(src))
+	c.update()
+}
+
+// Leave removes a container whose logs have ended.
+func (c *NameColumn) Leave(src Source) {
+	delete(c.widths, src.Name)
+	c.update()
+}
+
+func (c *NameColumn) update() {
+	c.width = 0
+	for _, w := range c.widths {
+		if w > c.width {
+			c.width = w
+		}
+	}
+}
+
+// Width returns the width of the widest name.
+func (c *NameColumn) Width() int {
+	return c.width
+}
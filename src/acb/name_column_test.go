@@ -0,0 +1,50 @@
+package dockerlogs
+
+import (
+	"testing"
+)
+
+// Ensure the column fits the containers being tailed as they come and go.
+func TestNameColumn(t *testing.T) {
+	c := &NameColumn{MaxWidth: 12, Abbreviate: true}
+	web := NewSource("shop_web_1", "", map[string]string{composeProjectLabel: "shop"})
+	worker := NewSource("worker", "", nil)
+	long := NewSource("determined_wozniak_hopper", "", nil)
+
+	for _, tt := range []struct {
+		src  Source
+		name string
+	}{
+		{src: web, name: "web_1"},
+		{src: worker, name: "worker"},
+		{src: long, name: "determined_…"},
+		{src: NewSource("x-api-2", "", map[string]string{composeProjectLabel: "x"}), name: "api-2"},
+		{src: NewSource("my_job_2", "", nil), name: "my_job_2"},
+	} {
+		if name := c.Name(tt.src); name != tt.name {
+			t.Errorf("%s: exp=%q got=%q", tt.src.Name, tt.name, name)
+		}
+	}
+
+	var steps = []struct {
+		join  bool
+		src   Source
+		width int
+	}{
+		{join: true, src: web, width: 5},
+		{join: true, src: worker, width: 6},
+		{join: true, src: long, width: 12},
+		{join: false, src: long, width: 6},
+		{join: false, src: worker, width: 5},
+	}
+	for i, tt := range steps {
+		if tt.join {
+			c.Join(tt.src)
+		} else {
+			c.Leave(tt.src)
+		}
+		if c.Width() != tt.width {
+			t.Errorf("%d. exp=%d got=%d", i, tt.width, c.Width())
+		}
+	}
+}
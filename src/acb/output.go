@@ -0,0 +1,356 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a parsed log line along with where and when it was received.
+// Source, Timestamp and Stream are not set for logs read from stdin.
+type Record struct {
+	Source Source
+	// Timestamp is the time docker received the line, see Log.Time for the
+	// time logged by the application.
+	Timestamp time.Time
+	Stream    string
+	// Line is the line as it was logged
+	Line string
+	Log  *Log
+	// Repeats is the number of logs a Collapser folded into this one,
+	// received from Timestamp until LastTimestamp; 0 for other records.
+	Repeats       int
+	LastTimestamp time.Time
+	// Latency is the time since the previous record of a Trace, set if it
+	// came from another container, i.e. Hop is set.
+	Hop     bool
+	Latency time.Duration
+}
+
+// Time returns the time logged by the application, or Timestamp if the line
+// did not include one.
+func (r *Record) Time() time.Time {
+	if r.Log != nil && !r.Log.Time.IsZero() {
+		return r.Log.Time
+	}
+	return r.Timestamp
+}
+
+// RecordWriter writes records in one of the OutputFormats.
+type RecordWriter interface {
+	Write(r *Record) error
+}
+
+// Output formats, as given to --output
+const (
+	OutputText   = "text"
+	OutputJSON   = "json"
+	OutputLogfmt = "logfmt"
+	OutputCSV    = "csv"
+	OutputRaw    = "raw"
+)
+
+var OutputFormats = []string{OutputText, OutputJSON, OutputLogfmt, OutputCSV, OutputRaw}
+
+// OutputOptions configures the text output; the machine readable formats
+// always include every column, and only share the Fields projection.
+type OutputOptions struct {
+	Formatter *Formatter
+	// Names lays out the container name column, nil omits it.
+	Names *NameColumn
+	// TimestampLayout formats the docker timestamp, "" omits it.
+	TimestampLayout string
+	// Template replaces the text layout, see ParseTemplate.
+	Template string
+	// Fields selects the context fields written in every format but raw.
+	Fields FieldProjection
+	// Relative replaces the timestamp with the time since the first or the
+	// previous record, see RelativeModes.
+	Relative string
+	// Location and Precision convert the times of records into a zone, and
+	// truncate them, in every format but raw; see TimestampLayout.
+	Location  *time.Location
+	Precision time.Duration
+}
+
+// Relative timestamp modes, as given to --relative
+const (
+	RelativeFirst    = "first"
+	RelativePrevious = "previous"
+)
+
+var RelativeModes = []string{RelativeFirst, RelativePrevious}
+
+func NewRecordWriter(format string, w io.Writer, opts OutputOptions) (RecordWriter, error) {
+	rw, err := newRecordWriter(format, w, opts)
+	if err != nil || format == OutputRaw {
+		return rw, err
+	}
+	if !opts.Fields.IsZero() {
+		rw = &projectionWriter{rw, opts.Fields}
+	}
+	if opts.Location != nil || opts.Precision > 0 {
+		rw = &timeWriter{rw, opts.Location, opts.Precision}
+	}
+	return rw, nil
+}
+
+func newRecordWriter(format string, w io.Writer, opts OutputOptions) (RecordWriter, error) {
+	switch format {
+	case OutputText:
+		if opts.Template != "" {
+			tmpl, err := ParseTemplate(opts.Template)
+			if err != nil {
+				return nil, err
+			}
+			return &templateWriter{w, tmpl}, nil
+		}
+		if opts.Formatter == nil {
+			opts.Formatter = DefaultFormatter
+		}
+		return &textWriter{w: w, opts: opts}, nil
+	case OutputJSON:
+		return &jsonWriter{json.NewEncoder(w)}, nil
+	case OutputLogfmt:
+		return &logfmtWriter{w}, nil
+	case OutputCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case OutputRaw:
+		return &rawWriter{w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+type textWriter struct {
+	w    io.Writer
+	opts OutputOptions
+	// first and prev are the times of the first and previous records, for
+	// relative timestamps
+	first, prev time.Time
+}
+
+// relative formats the time of a record relative to the first or previous
+// one, to the millisecond.
+func (t *textWriter) relative(r *Record) string {
+	now := r.Time()
+	if now.IsZero() {
+		return PadLeft("", 9)
+	}
+	if t.first.IsZero() {
+		t.first, t.prev = now, now
+	}
+	since := t.first
+	if t.opts.Relative == RelativePrevious {
+		since = t.prev
+	}
+	t.prev = now
+	return PadLeft(fmt.Sprintf("%+.3fs", now.Sub(since).Seconds()), 9)
+}
+
+// Write prints the name and timestamp columns followed by the formatted log,
+// whose continuation lines are indented past the columns. Collapsed records
+// are marked with their number of repeats, and hops of a trace with their
+// latency.
+func (t *textWriter) Write(r *Record) error {
+	buf := []string{}
+	if t.opts.Names != nil {
+		name := t.opts.Names.Name(r.Source)
+		buf = append(buf, paintContainer(r.Source, PadLeft(name, t.opts.Names.Width())))
+	}
+	if t.opts.Relative != "" {
+		buf = append(buf, paint(theme.Timestamp, t.relative(r)))
+	} else if t.opts.TimestampLayout != "" {
+		buf = append(buf, paint(theme.Timestamp, r.Timestamp.Format(t.opts.TimestampLayout)))
+	}
+	if r.Repeats > 0 {
+		repeats := fmt.Sprintf("[×%d]", r.Repeats)
+		if t.opts.TimestampLayout != "" {
+			repeats = fmt.Sprintf("[×%d until %s]", r.Repeats, r.LastTimestamp.Format(t.opts.TimestampLayout))
+		}
+		buf = append(buf, paint(theme.Caller, repeats))
+	}
+	if r.Hop {
+		buf = append(buf, paint(theme.Caller, "+"+formatLatency(r.Latency)))
+	}
+	f := *t.opts.Formatter
+	for _, s := range buf {
+		f.Indent += ansi.Width(s) + 1
+	}
+	buf = append(buf, f.Format(r.Log))
+	_, err := fmt.Fprintln(t.w, strings.Join(buf, " "))
+	return err
+}
+
+type rawWriter struct {
+	w io.Writer
+}
+
+func (t *rawWriter) Write(r *Record) error {
+	_, err := fmt.Fprintln(t.w, r.Line)
+	return err
+}
+
+// jsonRecord is the normalised form of a record used by the json output.
+type jsonRecord struct {
+	Container string    `json:"container,omitempty"`
+	Timestamp string    `json:"timestamp,omitempty"`
+	Time      string    `json:"time,omitempty"`
+	Stream    string    `json:"stream,omitempty"`
+	Level     string    `json:"level"`
+	LevelName string    `json:"level_name,omitempty"`
+	Inferred  bool      `json:"level_inferred,omitempty"`
+	Caller    string    `json:"caller,omitempty"`
+	Msg       string    `json:"msg"`
+	Fields    KeyValues `json:"fields"`
+	// Repeats and LastTimestamp are set for collapsed records
+	Repeats       int    `json:"repeats,omitempty"`
+	LastTimestamp string `json:"last_timestamp,omitempty"`
+	// Latency is set for the hops of a trace
+	Latency string `json:"latency,omitempty"`
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatLatency rounds a latency to a precision which suits its size.
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second || d <= -time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond || d <= -time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
+
+func newJSONRecord(r *Record) *jsonRecord {
+	fields := r.Log.Context
+	if fields == nil {
+		fields = KeyValues{}
+	}
+	latency := ""
+	if r.Hop {
+		latency = formatLatency(r.Latency)
+	}
+	return &jsonRecord{
+		Container: r.Source.Name,
+		Timestamp: formatTime(r.Timestamp),
+		Time:      formatTime(r.Log.Time),
+		Stream:    r.Stream,
+		Level:     r.Log.Level.String(),
+		LevelName: r.Log.LevelName,
+		Inferred:  r.Log.LevelInferred,
+		Caller:    r.Log.Caller,
+		Msg:       ansi.Strip(r.Log.Msg),
+		Fields:    fields,
+
+		Repeats:       r.Repeats,
+		LastTimestamp: formatTime(r.LastTimestamp),
+		Latency:       latency,
+	}
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (t *jsonWriter) Write(r *Record) error {
+	return t.enc.Encode(newJSONRecord(r))
+}
+
+// logfmtValue renders a value for logfmt, quoting it if required. Arrays and
+// objects are encoded as json.
+func logfmtValue(v Value) string {
+	s := v.String()
+	if v.Kind == ArrayKind || v.Kind == ObjectKind {
+		b, err := json.Marshal(v)
+		if err == nil {
+			s = string(b)
+		}
+	}
+	return logfmtQuote(s)
+}
+
+func logfmtQuote(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) != -1 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+type logfmtWriter struct {
+	w io.Writer
+}
+
+func (t *logfmtWriter) Write(r *Record) error {
+	jr := newJSONRecord(r)
+	buf := []string{}
+	for _, kv := range [][2]string{
+		{"container", jr.Container},
+		{"timestamp", jr.Timestamp},
+		{"time", jr.Time},
+		{"stream", jr.Stream},
+		{"level", jr.Level},
+		{"caller", jr.Caller},
+	} {
+		if kv[1] != "" {
+			buf = append(buf, kv[0]+"="+logfmtQuote(kv[1]))
+		}
+	}
+	buf = append(buf, "msg="+logfmtQuote(jr.Msg))
+	for _, kv := range jr.Fields {
+		buf = append(buf, logfmtQuote(kv.Key)+"="+logfmtValue(kv.Value))
+	}
+	if jr.Repeats > 0 {
+		buf = append(buf, "repeats="+strconv.Itoa(jr.Repeats))
+		if jr.LastTimestamp != "" {
+			buf = append(buf, "last_timestamp="+jr.LastTimestamp)
+		}
+	}
+	if jr.Latency != "" {
+		buf = append(buf, "latency="+jr.Latency)
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(buf, " "))
+	return err
+}
+
+var csvHeader = []string{"container", "timestamp", "time", "stream", "level", "caller", "msg", "fields"}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// Write writes a row per record, with the fields encoded as a json object.
+// The header row is written before the first record.
+func (t *csvWriter) Write(r *Record) error {
+	if !t.headerWritten {
+		t.headerWritten = true
+		if err := t.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	jr := newJSONRecord(r)
+	fields, err := json.Marshal(jr.Fields)
+	if err != nil {
+		return err
+	}
+	if err := t.w.Write([]string{jr.Container, jr.Timestamp, jr.Time, jr.Stream, jr.Level, jr.Caller, jr.Msg, string(fields)}); err != nil {
+		return err
+	}
+	t.w.Flush()
+	return t.w.Error()
+}
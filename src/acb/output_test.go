@@ -0,0 +1,116 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"bytes"
+	"testing"
+	"time"
+)
+
+// Ensure the machine readable outputs include the normalised record.
+func TestRecordWriter(t *testing.T) {
+	r := &Record{
+		Source:    NewSource("web", "", nil),
+		Timestamp: time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC),
+		Stream:    Stderr,
+		Line:      `{"level":"warn","msg":"slow request","path":"/a b","tags":["x","y"]}`,
+	}
+	r.Log = ParseLog(r.Line)
+
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{OutputJSON, `{"container":"web","timestamp":"2017-01-01T10:00:00Z","stream":"stderr","level":"warning","level_name":"warn","msg":"slow request","fields":{"path":"/a b","tags":["x","y"]}}` + "\n"},
+		{OutputLogfmt, `container=web timestamp=2017-01-01T10:00:00Z stream=stderr level=warning msg="slow request" path="/a b" tags="[\"x\",\"y\"]"` + "\n"},
+		{OutputCSV, "container,timestamp,time,stream,level,caller,msg,fields\n" +
+			`web,2017-01-01T10:00:00Z,,stderr,warning,,slow request,"{""path"":""/a b"",""tags"":[""x"",""y""]}"` + "\n"},
+		{OutputRaw, r.Line + "\n"},
+	} {
+		var buf bytes.Buffer
+		w, err := NewRecordWriter(tc.format, &buf, OutputOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tc.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.format, tc.exp, buf.String())
+		}
+	}
+
+	if _, err := NewRecordWriter("xml", &bytes.Buffer{}, OutputOptions{}); err == nil {
+		t.Errorf("expected unknown output format error")
+	}
+}
+
+// Ensure collapsed records include their repeats.
+func TestRecordWriter_Repeats(t *testing.T) {
+	r := &Record{
+		Source:        NewSource("web", "", nil),
+		Timestamp:     time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC),
+		Line:          "connection refused",
+		Log:           &Log{Level: ERROR, Msg: "connection refused"},
+		Repeats:       3,
+		LastTimestamp: time.Date(2017, 1, 1, 10, 0, 2, 0, time.UTC),
+	}
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	SetColors(DarkTheme, ansi.NoColor)
+
+	for _, tc := range []struct {
+		format string
+		opts   OutputOptions
+		exp    string
+	}{
+		{OutputText, OutputOptions{TimestampLayout: "15:04:05"}, "10:00:00 [×3 until 10:00:02] ERR connection refused\n"},
+		{OutputLogfmt, OutputOptions{}, "container=web timestamp=2017-01-01T10:00:00Z level=error msg=\"connection refused\" repeats=3 last_timestamp=2017-01-01T10:00:02Z\n"},
+		{OutputJSON, OutputOptions{}, `{"container":"web","timestamp":"2017-01-01T10:00:00Z","level":"error","msg":"connection refused","fields":{},"repeats":3,"last_timestamp":"2017-01-01T10:00:02Z"}` + "\n"},
+	} {
+		var buf bytes.Buffer
+		w, err := NewRecordWriter(tc.format, &buf, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tc.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.format, tc.exp, buf.String())
+		}
+	}
+}
+
+// Ensure relative timestamps are measured from the first or the previous
+// record, using the time logged by the application.
+func TestRecordWriter_Relative(t *testing.T) {
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	SetColors(DarkTheme, ansi.NoColor)
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		mode string
+		exp  string
+	}{
+		{RelativeFirst, "  +0.000s UNK a\n  +0.250s UNK b\n  +1.255s UNK c\n"},
+		{RelativePrevious, "  +0.000s UNK a\n  +0.250s UNK b\n  +1.005s UNK c\n"},
+	} {
+		var buf bytes.Buffer
+		w, err := NewRecordWriter(OutputText, &buf, OutputOptions{TimestampLayout: "15:04:05", Relative: tc.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, ms := range []int{0, 250, 1255} {
+			r := &Record{
+				Timestamp: start,
+				Log:       &Log{Msg: string(rune('a' + i)), Time: start.Add(time.Duration(ms) * time.Millisecond)},
+			}
+			if err := w.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if buf.String() != tc.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.mode, tc.exp, buf.String())
+		}
+	}
+}
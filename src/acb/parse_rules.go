@@ -0,0 +1,177 @@
+package dockerlogs
+
+import (
+	"acb/logparsers/layout"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ParseRule is a user defined layout, optionally restricted to containers
+// whose name or image matches one of the given glob patterns.
+type ParseRule struct {
+	Layout     *layout.Layout
+	Containers []string
+	Images     []string
+}
+
+type parseRuleConfig struct {
+	Name       string            `json:"name"`
+	Containers []string          `json:"containers"`
+	Images     []string          `json:"images"`
+	Regexp     string            `json:"regexp"`
+	TimeLayout string            `json:"time_layout"`
+	Levels     map[string]string `json:"levels"`
+	Groups     map[string]string `json:"groups"`
+}
+
+type parseRulesConfig struct {
+	Rules []parseRuleConfig `json:"rules"`
+	Infer []levelRuleConfig `json:"infer"`
+}
+
+var parseRules []*ParseRule
+
+// ConfigDir returns the directory holding the dockerlogs config files,
+// i.e. $XDG_CONFIG_HOME/dockerlogs or ~/.config/dockerlogs
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "dockerlogs")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "dockerlogs")
+}
+
+// DefaultParseRulesPath returns the path of the user defined parse rules.
+func DefaultParseRulesPath() string {
+	return filepath.Join(ConfigDir(), "parsers.json")
+}
+
+// LoadParseRules reads user defined parse rules from a json file such as
+//
+//	{"rules": [{
+//	    "name": "billing",
+//	    "containers": ["billing*"],
+//	    "regexp": "^(?P<time>\\S+) <(?P<lvl>\\w)> (?P<msg>.*)$",
+//	    "time_layout": "2006-01-02T15:04:05Z07:00",
+//	    "levels": {"W": "warning", "E": "error"},
+//	    "groups": {"lvl": "level"}
+//	}],
+//	"infer": [
+//	    {"level": "error", "regexp": "\\bfailed\\b"}
+//	]}
+//
+// If given, the infer rules replace DefaultLevelRules. A missing file is not
+// an error.
+func LoadParseRules(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config := parseRulesConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	rules := []*ParseRule{}
+	for i, rc := range config.Rules {
+		if rc.Name == "" {
+			rc.Name = fmt.Sprintf("rule%d", i+1)
+		}
+		for _, pattern := range append(append([]string{}, rc.Containers...), rc.Images...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: rule %s: bad pattern %q", filename, rc.Name, pattern)
+			}
+		}
+		if rc.Regexp == "" {
+			return fmt.Errorf("%s: rule %s: missing regexp", filename, rc.Name)
+		}
+		l, err := layout.New(rc.Name, rc.Regexp, rc.TimeLayout)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		l.Levels = rc.Levels
+		l.Groups = rc.Groups
+		rules = append(rules, &ParseRule{
+			Layout:     l,
+			Containers: rc.Containers,
+			Images:     rc.Images,
+		})
+	}
+	parseRules = rules
+
+	if config.Infer != nil {
+		inferRules, err := compileLevelRules(config.Infer)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		levelRules = inferRules
+	}
+	return nil
+}
+
+// Matches returns true if the rule applies to the source. A rule without any
+// container or image patterns applies to all sources. Image patterns match
+// the full image reference or its repository name, without the registry and
+// tag if they are left out of the pattern, so that billing* and acme/billing
+// match registry.acme.com/acme/billing:1.2.
+func (r *ParseRule) Matches(src Source) bool {
+	if len(r.Containers) == 0 && len(r.Images) == 0 {
+		return true
+	}
+	if matchAny(r.Containers, src.Name) {
+		return true
+	}
+	for _, name := range imageNames(src.Image) {
+		if matchAny(r.Images, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageNames returns the names an image is matched by: the reference itself,
+// and its repository name without the tag or digest, as a whole and without
+// each of its leading path components.
+func imageNames(image string) []string {
+	names := []string{image}
+	repo := image
+	if i := strings.IndexByte(repo, '@'); i != -1 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndexByte(repo, ':'); i > strings.LastIndexByte(repo, '/') {
+		repo = repo[:i]
+	}
+	for {
+		names = append(names, repo)
+		i := strings.IndexByte(repo, '/')
+		if i == -1 {
+			return names
+		}
+		repo = repo[i+1:]
+	}
+}
+
+// Format returns the rule as a format named after the rule.
+func (r *ParseRule) Format() *Format {
+	return layoutFormat(r.Layout)
+}
+
+func matchAny(patterns []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package dockerlogs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Ensure parse rules are loaded and applied to the containers they are scoped
+// to, ahead of the builtin formats and in the order they are given.
+func TestLoadParseRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(rules []*ParseRule, infer []LevelRule) {
+		parseRules, levelRules = rules, infer
+	}(parseRules, levelRules)
+
+	filename := filepath.Join(dir, "parsers.json")
+	if err := LoadParseRules(filename); err != nil {
+		t.Fatalf("expected a missing file to be ignored, got %v", err)
+	}
+
+	config := `{"rules": [
+		{"name": "billing", "images": ["billing*"], "regexp": "^<(?P<level>\\w)> (?P<msg>.*)$", "levels": {"W": "warning"}},
+		{"name": "any", "regexp": "^<(?P<msg>.*)> (?P<tail>.*)$"},
+		{"containers": ["web-*"], "regexp": "^(?P<msg>.*)!$"}
+	], "infer": [{"level": "error", "regexp": "\\bboom\\b"}]}`
+	if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadParseRules(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(parseRules) != 3 || parseRules[2].Layout.Name != "rule3" {
+		t.Fatalf("expected 3 rules with the last one named rule3, got %d", len(parseRules))
+	}
+	if len(levelRules) != 1 || levelRules[0].Level != ERROR {
+		t.Errorf("expected the infer rules to replace the defaults, got %v", levelRules)
+	}
+
+	var tests = []struct {
+		src    Source
+		format string
+		level  LogLevel
+	}{
+		{src: Source{Name: "invoices", Image: "registry.acme.com:5000/acme/billing:1.2"}, format: "billing", level: WARNING},
+		{src: Source{Name: "invoices", Image: "billing@sha256:1234"}, format: "billing", level: WARNING},
+		{src: Source{Name: "invoices", Image: "acme/payments:1.2"}, format: "any", level: UNKNOWN},
+		{src: Source{Name: "web-1", Image: "nginx"}, format: "any", level: UNKNOWN},
+	}
+	for i, tt := range tests {
+		log, format := parseFirst(formatsFor(tt.src), `<W> paid!`)
+		if format.Name != tt.format || log.Level != tt.level {
+			t.Errorf("%d. %s: exp=%s (%s) got=%s (%s)", i, tt.src.Image, tt.format, tt.level, format.Name, log.Level)
+		}
+	}
+
+	web := Source{Name: "web-1", Image: "nginx"}
+	if _, format := parseFirst(formatsFor(web), `paid!`); format.Name != "rule3" {
+		t.Errorf("expected rule3 for web-1, got %s", format.Name)
+	}
+	if _, format := parseFirst(formatsFor(Source{Name: "api-1"}), `paid!`); format.Name != RawFormat.Name {
+		t.Errorf("expected rule3 not to apply to api-1, got %s", format.Name)
+	}
+
+	for _, config := range []string{
+		`{"rules": [`,
+		`{"rules": [{"name": "x"}]}`,
+		`{"rules": [{"name": "x", "regexp": "("}]}`,
+		`{"rules": [{"name": "x", "regexp": "x", "images": ["["]}]}`,
+		`{"infer": [{"level": "loud", "regexp": "x"}]}`,
+	} {
+		if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := LoadParseRules(filename); err == nil {
+			t.Errorf("%s: expected error", config)
+		}
+	}
+}
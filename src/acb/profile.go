@@ -0,0 +1,167 @@
+package dockerlogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Profile is a named set of flags and containers, selected with --profile.
+type Profile struct {
+	Containers []string `json:"containers"`
+	// Flags maps flag names onto their values: strings, numbers, booleans
+	// or, for flags which can be repeated, lists of them.
+	Flags map[string]interface{} `json:"flags"`
+}
+
+func DefaultProfilesPath() string {
+	return filepath.Join(ConfigDir(), "profiles.json")
+}
+
+// LoadProfiles reads profiles from a json file such as
+// ~/.config/dockerlogs/profiles.json, e.g.
+//
+//	{"checkout-debug": {
+//	    "containers": ["checkout", "payments"],
+//	    "flags": {
+//	        "where": "level>=warn || status>=500",
+//	        "highlight": ["user_id=1234", "/req-[0-9a-f]+/"],
+//	        "hide-fields": "hostname,pid",
+//	        "collapse": true,
+//	        "theme": "light"
+//	    }
+//	}}
+//
+// A missing file holds no profiles.
+func LoadProfiles(filename string) (map[string]*Profile, error) {
+	profiles := map[string]*Profile{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return profiles, nil
+}
+
+// flagArgs returns the arguments which set a flag to a profile value.
+func flagArgs(name string, value interface{}, list bool) ([]string, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return []string{"--" + name}, nil
+		}
+		return []string{"--no-" + name}, nil
+	case string:
+		return []string{"--" + name + "=" + v}, nil
+	case float64:
+		return []string{"--" + name + "=" + strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case []interface{}:
+		if list && len(v) == 0 {
+			// an empty value, e.g. to turn off the default --block fields
+			return []string{"--" + name + "="}, nil
+		}
+		if list {
+			args := []string{}
+			for _, x := range v {
+				a, err := flagArgs(name, x, false)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, a...)
+			}
+			return args, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v for --%s", value, name)
+}
+
+// ProfileArgs returns the command line arguments args, preceded by those of
+// the profile given to the --profile flag in them, if any. Flags in args
+// replace those of the profile, unless they can be repeated in which case
+// they add to them; arguments, i.e. containers, in args replace those of the
+// profile.
+func ProfileArgs(app *kingpin.Application, args []string, filename string) ([]string, error) {
+	context, err := app.ParseContext(args)
+	if err != nil {
+		// left for app.Parse to report
+		return args, nil
+	}
+	name := ""
+	given := map[string]bool{}
+	containersGiven := false
+	for _, e := range context.Elements {
+		switch c := e.Clause.(type) {
+		case *kingpin.FlagClause:
+			model := c.Model()
+			given[model.Name] = true
+			if model.Name == "profile" && e.Value != nil {
+				name = *e.Value
+			}
+		case *kingpin.ArgClause:
+			containersGiven = true
+		}
+	}
+	if name == "" {
+		return args, nil
+	}
+
+	profiles, err := LoadProfiles(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	model := app.Model()
+	flags := map[string]*kingpin.FlagModel{}
+	for _, f := range model.Flags {
+		flags[f.Name] = f
+	}
+	names := []string{}
+	for n := range p.Flags {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	profileArgs := []string{}
+	for _, n := range names {
+		f, ok := flags[n]
+		if !ok || n == "profile" {
+			return nil, fmt.Errorf("profile %s: unknown flag --%s", name, n)
+		}
+		cumulative, ok := f.Value.(interface {
+			IsCumulative() bool
+		})
+		list := ok && cumulative.IsCumulative()
+		if given[n] && !list {
+			continue
+		}
+		a, err := flagArgs(n, p.Flags[n], list)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+		profileArgs = append(profileArgs, a...)
+	}
+	args = append(profileArgs, args...)
+
+	if len(p.Containers) > 0 && !containersGiven {
+		if len(model.Args) == 0 {
+			return nil, fmt.Errorf("profile %s: %s does not take containers", name, model.Name)
+		}
+		args = append(args, p.Containers...)
+	}
+	return args, nil
+}
@@ -0,0 +1,106 @@
+package dockerlogs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Ensure profiles are expanded into flags, which those on the command line
+// replace or add to.
+func TestProfileArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "profiles.json")
+	config := `{
+		"debug": {
+			"containers": ["web", "db"],
+			"flags": {"where": "level>=warn", "highlight": ["a", "b"], "collapse": true, "infer": false, "rate": 2.5}
+		},
+		"bad": {"flags": {"unknown": "x"}}
+	}`
+	if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newApp := func() (*kingpin.Application, map[string]interface{}) {
+		app := kingpin.New("test", "")
+		app.Terminate(nil)
+		values := map[string]interface{}{
+			"profile":    app.Flag("profile", "").Short('p').String(),
+			"where":      app.Flag("where", "").String(),
+			"highlight":  app.Flag("highlight", "").Strings(),
+			"collapse":   app.Flag("collapse", "").Bool(),
+			"infer":      app.Flag("infer", "").Default("true").Bool(),
+			"rate":       app.Flag("rate", "").Float64(),
+			"containers": app.Arg("container", "").Strings(),
+		}
+		return app, values
+	}
+
+	for _, tc := range []struct {
+		args []string
+		exp  map[string]interface{}
+	}{
+		{[]string{}, map[string]interface{}{"where": "", "highlight": []string(nil), "collapse": false, "infer": true, "containers": []string(nil)}},
+		{[]string{"-p", "debug"}, map[string]interface{}{"where": "level>=warn", "highlight": []string{"a", "b"}, "collapse": true, "infer": false, "rate": 2.5, "containers": []string{"web", "db"}}},
+		{[]string{"--where", "x", "--highlight", "c", "-p", "debug", "api"}, map[string]interface{}{"where": "x", "highlight": []string{"a", "b", "c"}, "containers": []string{"api"}}},
+		{[]string{"-pdebug", "--no-collapse", "--infer"}, map[string]interface{}{"collapse": false, "infer": true}},
+	} {
+		app, values := newApp()
+		args, err := ProfileArgs(app, tc.args, filename)
+		if err != nil {
+			t.Errorf("%v: %v", tc.args, err)
+			continue
+		}
+		if _, err := app.Parse(args); err != nil {
+			t.Errorf("%v: %v", args, err)
+			continue
+		}
+		for name, exp := range tc.exp {
+			if got := reflect.ValueOf(values[name]).Elem().Interface(); !reflect.DeepEqual(got, exp) {
+				t.Errorf("%v: --%s exp=%v got=%v", tc.args, name, exp, got)
+			}
+		}
+	}
+
+	for _, args := range [][]string{{"-p", "bad"}, {"-p", "missing"}} {
+		app, _ := newApp()
+		if _, err := ProfileArgs(app, args, filename); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+// Ensure an empty list in a profile clears a flag's default list.
+func TestProfileArgs_EmptyList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "profiles.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"flat": {"flags": {"block": []}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := kingpin.New("test", "")
+	flags := NewFlags(app)
+	args, err := ProfileArgs(app, []string{"-p", "flat"}, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Parse(args); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	if got := flags.FieldOrder().Block; len(got) != 0 {
+		t.Errorf("%v: expected no block fields, got %q", args, got)
+	}
+}
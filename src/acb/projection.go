@@ -0,0 +1,62 @@
+package dockerlogs
+
+// FieldProjection selects the context fields which are output. Fields are
+// matched by their path, such as request_id or http.method for a nested
+// field, against glob patterns such as http.* or *.id.
+type FieldProjection struct {
+	// Include, if not empty, keeps only the matching fields; an object is
+	// kept if it, or any field in it, matches.
+	Include []string
+	// Exclude drops the matching fields, even if they are included.
+	Exclude []string
+}
+
+// IsZero returns true if the projection keeps every field.
+func (p FieldProjection) IsZero() bool {
+	return len(p.Include) == 0 && len(p.Exclude) == 0
+}
+
+// Apply returns the fields selected by the projection, in the order they
+// were logged.
+func (p FieldProjection) Apply(fields KeyValues) KeyValues {
+	if p.IsZero() {
+		return fields
+	}
+	return p.apply(fields, "", len(p.Include) == 0)
+}
+
+// apply projects fields nested under prefix; included is true if their
+// parent was included.
+func (p FieldProjection) apply(fields KeyValues, prefix string, included bool) KeyValues {
+	out := KeyValues{}
+	for _, kv := range fields {
+		name := prefix + kv.Key
+		if matchAny(p.Exclude, name) {
+			continue
+		}
+		in := included || matchAny(p.Include, name)
+		if kv.Value.Kind == ObjectKind {
+			object := p.apply(kv.Value.Object, name+".", in)
+			if in || len(object) > 0 {
+				out = append(out, KeyValue{kv.Key, ObjectValue(object)})
+			}
+		} else if in {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+type projectionWriter struct {
+	w          RecordWriter
+	projection FieldProjection
+}
+
+// Write writes a copy of the record with the projected fields.
+func (p *projectionWriter) Write(r *Record) error {
+	l := *r.Log
+	l.Context = p.projection.Apply(l.Context)
+	projected := *r
+	projected.Log = &l
+	return p.w.Write(&projected)
+}
@@ -0,0 +1,54 @@
+package dockerlogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// Ensure fields are selected by their path, keeping the objects which lead
+// to them.
+func TestFieldProjection_Apply(t *testing.T) {
+	l := ParseLog(`{"msg":"done","request_id":"r1","hostname":"h","pid":1,"http":{"method":"GET","status":200,"user":{"id":"u1","name":"n"}}}`)
+	for _, tc := range []struct {
+		include, exclude []string
+		exp              string
+	}{
+		{nil, nil, `{"request_id":"r1","hostname":"h","pid":1,"http":{"method":"GET","status":200,"user":{"id":"u1","name":"n"}}}`},
+		{[]string{"request_id", "http.status"}, nil, `{"request_id":"r1","http":{"status":200}}`},
+		{nil, []string{"hostname", "pid"}, `{"request_id":"r1","http":{"method":"GET","status":200,"user":{"id":"u1","name":"n"}}}`},
+		{[]string{"http"}, []string{"http.user"}, `{"http":{"method":"GET","status":200}}`},
+		{[]string{"*.id"}, nil, `{"http":{"user":{"id":"u1"}}}`},
+		{[]string{"http.*"}, []string{"*.name"}, `{"http":{"method":"GET","status":200,"user":{"id":"u1"}}}`},
+		{[]string{"missing"}, nil, `{}`},
+	} {
+		p := FieldProjection{Include: tc.include, Exclude: tc.exclude}
+		b, err := json.Marshal(p.Apply(l.Context))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.exp {
+			t.Errorf("%v %v: exp=%s got=%s", tc.include, tc.exclude, tc.exp, b)
+		}
+	}
+}
+
+// Ensure the projection applies to the machine readable outputs.
+func TestRecordWriter_Fields(t *testing.T) {
+	r := &Record{Line: `{"msg":"done","request_id":"r1","pid":1}`}
+	r.Log = ParseLog(r.Line)
+	var buf bytes.Buffer
+	w, err := NewRecordWriter(OutputLogfmt, &buf, OutputOptions{Fields: FieldProjection{Exclude: []string{"pid"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(r); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "level=unknown msg=done request_id=r1\n"; buf.String() != exp {
+		t.Errorf("exp=%q got=%q", exp, buf.String())
+	}
+	if len(r.Log.Context) != 2 {
+		t.Errorf("expected the record not to be modified, got %v", r.Log.Context)
+	}
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Value is the value of a field, or a value it is compared to. Numbers keep
+// their text in Str.
+type Value struct {
+	Str   string
+	Num   float64
+	IsNum bool
+}
+
+// String returns a string value.
+func String(s string) Value {
+	return Value{Str: s}
+}
+
+// Number returns a numeric value.
+func Number(f float64) Value {
+	return Value{Str: strconv.FormatFloat(f, 'g', -1, 64), Num: f, IsNum: true}
+}
+
+// number returns the value as a number, parsing strings such as "500".
+func (v Value) number() (float64, bool) {
+	if v.IsNum {
+		return v.Num, true
+	}
+	f, err := strconv.ParseFloat(v.Str, 64)
+	return f, err == nil
+}
+
+// Record is what expressions are evaluated against.
+type Record interface {
+	// Field returns the value of a field, and false if it is not set.
+	Field(name string) (Value, bool)
+}
+
+// Expr is a parsed expression.
+type Expr interface {
+	// Eval returns true if the record matches the expression.
+	Eval(r Record) bool
+	String() string
+}
+
+// BinaryExpr combines two expressions with AND or OR.
+type BinaryExpr struct {
+	Op       Token
+	LHS, RHS Expr
+}
+
+func (e *BinaryExpr) Eval(r Record) bool {
+	if e.Op == AND {
+		return e.LHS.Eval(r) && e.RHS.Eval(r)
+	}
+	return e.LHS.Eval(r) || e.RHS.Eval(r)
+}
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
+
+// NotExpr negates an expression.
+type NotExpr struct {
+	Expr Expr
+}
+
+func (e *NotExpr) Eval(r Record) bool {
+	return !e.Expr.Eval(r)
+}
+
+func (e *NotExpr) String() string {
+	return fmt.Sprintf("!%s", e.Expr)
+}
+
+// Exists matches records in which a field is set.
+type Exists struct {
+	Field string
+}
+
+func (e *Exists) Eval(r Record) bool {
+	_, ok := r.Field(e.Field)
+	return ok
+}
+
+func (e *Exists) String() string {
+	return e.Field
+}
+
+// Comparison compares a field to a value. Values are compared as numbers if
+// both are numeric, otherwise as strings. Comparisons on a field which is
+// not set are false.
+type Comparison struct {
+	Field string
+	Op    Token
+	Value Value
+
+	re *regexp.Regexp
+}
+
+func (c *Comparison) Eval(r Record) bool {
+	v, ok := r.Field(c.Field)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case MATCH:
+		return c.re.MatchString(v.Str)
+	case NOTMATCH:
+		return !c.re.MatchString(v.Str)
+	case CONTAINS:
+		return strings.Contains(strings.ToLower(v.Str), strings.ToLower(c.Value.Str))
+	}
+
+	cmp := 0
+	a, aok := v.number()
+	b, bok := c.Value.number()
+	if bok && !aok && c.Op != EQ && c.Op != NEQ {
+		// values which are not numbers are not ordered against numbers
+		return false
+	}
+	switch {
+	case aok && bok && a < b, (!aok || !bok) && v.Str < c.Value.Str:
+		cmp = -1
+	case aok && bok && a > b, (!aok || !bok) && v.Str > c.Value.Str:
+		cmp = 1
+	}
+	switch c.Op {
+	case EQ:
+		return cmp == 0
+	case NEQ:
+		return cmp != 0
+	case LT:
+		return cmp < 0
+	case LTE:
+		return cmp <= 0
+	case GT:
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func (c *Comparison) String() string {
+	return fmt.Sprintf("%s%s%s", c.Field, c.Op, strconv.Quote(c.Value.Str))
+}
+
+// Inspect calls fn for each comparison in the expression, stopping at the
+// first error. It can be used to check or convert the values fields are
+// compared to.
+func Inspect(e Expr, fn func(c *Comparison) error) error {
+	switch e := e.(type) {
+	case *BinaryExpr:
+		if err := Inspect(e.LHS, fn); err != nil {
+			return err
+		}
+		return Inspect(e.RHS, fn)
+	case *NotExpr:
+		return Inspect(e.Expr, fn)
+	case *Comparison:
+		return fn(e)
+	}
+	return nil
+}
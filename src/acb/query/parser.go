@@ -0,0 +1,196 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parser represents a parser.
+type Parser struct {
+	s   *Scanner
+	buf struct {
+		tok Token  // last read token
+		lit string // last read literal
+		pos int    // position of the last read token
+		n   int    // buffer size (max=1)
+	}
+}
+
+// NewParser returns a new instance of Parser.
+func NewParser(s string) *Parser {
+	return &Parser{s: NewScanner(strings.NewReader(s))}
+}
+
+// Parse parses an expression such as
+//
+//	level>=warn && container=~"api.*" && status>=500 && !msg~"healthcheck"
+//
+// Fields are compared using =, !=, <, <=, >, >=, =~ (regexp match), !~ (no
+// regexp match) or ~ (contains, ignoring case); a field on its own matches
+// if it is set. Expressions are combined using &&, || and !, or and, or and
+// not, and grouped using parentheses.
+func Parse(s string) (Expr, error) {
+	return NewParser(s).Parse()
+}
+
+// Parse parses the whole expression.
+func (p *Parser) Parse() (Expr, error) {
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok, lit, pos := p.scanIgnoreWhitespace(); tok != EOF {
+		return nil, errorf(pos, "found %s, expected && or ||", describe(tok, lit))
+	}
+	return expr, nil
+}
+
+func (p *Parser) parseOr() (Expr, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != OR {
+			p.unscan()
+			return expr, nil
+		}
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expr = &BinaryExpr{Op: OR, LHS: expr, RHS: rhs}
+	}
+}
+
+func (p *Parser) parseAnd() (Expr, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != AND {
+			p.unscan()
+			return expr, nil
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		expr = &BinaryExpr{Op: AND, LHS: expr, RHS: rhs}
+	}
+}
+
+func (p *Parser) parseUnary() (Expr, error) {
+	tok, lit, pos := p.scanIgnoreWhitespace()
+	switch tok {
+	case NOT:
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{expr}, nil
+	case LPAREN:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok, lit, pos := p.scanIgnoreWhitespace(); tok != RPAREN {
+			return nil, errorf(pos, "found %s, expected )", describe(tok, lit))
+		}
+		return expr, nil
+	case IDENT, STRING:
+		return p.parseComparison(lit)
+	}
+	return nil, errorf(pos, "found %s, expected field, ! or (", describe(tok, lit))
+}
+
+// parseComparison parses what follows a field name.
+func (p *Parser) parseComparison(field string) (Expr, error) {
+	op, _, _ := p.scanIgnoreWhitespace()
+	if !op.isComparison() {
+		p.unscan()
+		return &Exists{field}, nil
+	}
+
+	tok, lit, pos := p.scanIgnoreWhitespace()
+	if tok != IDENT && tok != STRING {
+		return nil, errorf(pos, "found %s, expected value after %s%s", describe(tok, lit), field, op)
+	}
+	c := &Comparison{Field: field, Op: op, Value: String(lit)}
+	if tok == IDENT {
+		if f, err := strconv.ParseFloat(lit, 64); err == nil {
+			c.Value = Value{Str: lit, Num: f, IsNum: true}
+		}
+	}
+	if op == MATCH || op == NOTMATCH {
+		re, err := regexp.Compile(lit)
+		if err != nil {
+			return nil, errorf(pos, "bad regexp %q: %v", lit, strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// Error is a syntax error, at a position (in runes, starting at 1) in the
+// expression.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// describe returns a token for use in an error message.
+func describe(tok Token, lit string) string {
+	switch tok {
+	case EOF:
+		return tok.String()
+	case STRING:
+		return fmt.Sprintf("%q", lit)
+	case ILLEGAL:
+		if strings.HasPrefix(lit, "\"") {
+			return "unterminated string"
+		}
+	}
+	return fmt.Sprintf("%q", lit)
+}
+
+// scan returns the next token from the underlying scanner.
+// If a token has been unscanned then read that instead.
+func (p *Parser) scan() (tok Token, lit string, pos int) {
+	// If we have a token on the buffer, then return it.
+	if p.buf.n != 0 {
+		p.buf.n = 0
+		return p.buf.tok, p.buf.lit, p.buf.pos
+	}
+
+	// Otherwise read the next token from the scanner.
+	tok, lit, pos = p.s.Scan()
+
+	// Save it to the buffer in case we unscan later.
+	p.buf.tok, p.buf.lit, p.buf.pos = tok, lit, pos
+
+	return
+}
+
+// scanIgnoreWhitespace scans the next non-whitespace token.
+func (p *Parser) scanIgnoreWhitespace() (tok Token, lit string, pos int) {
+	tok, lit, pos = p.scan()
+	if tok == WS {
+		tok, lit, pos = p.scan()
+	}
+	return
+}
+
+// unscan pushes the previously read token back onto the buffer.
+func (p *Parser) unscan() { p.buf.n = 1 }
@@ -0,0 +1,101 @@
+package query_test
+
+import (
+	"acb/query"
+	"testing"
+)
+
+// Ensure the parser can parse expressions, and reports where they are wrong.
+func TestParser_Parse(t *testing.T) {
+	var tests = []struct {
+		s    string
+		expr string
+		err  string
+	}{
+		{s: `status>=500`, expr: `status>="500"`},
+		{s: `a && b || c`, expr: `((a && b) || c)`},
+		{s: `a || b && c`, expr: `(a || (b && c))`},
+		{s: `a and not (b or c)`, expr: `(a && !(b || c))`},
+		{s: `!msg~"health check"`, expr: `!msg~"health check"`},
+		{s: `container=~"api.*" && level!=debug`, expr: `(container=~"api.*" && level!="debug")`},
+		{s: `"user id"=1`, expr: `user id="1"`},
+
+		{s: ``, err: `column 1: found end of expression, expected field, ! or (`},
+		{s: `a &&`, err: `column 5: found end of expression, expected field, ! or (`},
+		{s: `a = `, err: `column 5: found end of expression, expected value after a=`},
+		{s: `a = (`, err: `column 5: found "(", expected value after a=`},
+		{s: `(a`, err: `column 3: found end of expression, expected )`},
+		{s: `a b`, err: `column 3: found "b", expected && or ||`},
+		{s: `a & b`, err: `column 3: found "&", expected && or ||`},
+		{s: `msg="abc`, err: `column 5: found unterminated string, expected value after msg=`},
+		{s: `msg=~"(a"`, err: "column 6: bad regexp \"(a\": missing closing ): `(a`"},
+	}
+
+	for i, tt := range tests {
+		expr, err := query.Parse(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && expr.String() != tt.expr {
+			t.Errorf("%d. %q: exp=%s got=%s", i, tt.s, tt.expr, expr)
+		}
+	}
+}
+
+type record map[string]query.Value
+
+func (r record) Field(name string) (query.Value, bool) {
+	v, ok := r[name]
+	return v, ok
+}
+
+// Ensure expressions are evaluated against records.
+func TestExpr_Eval(t *testing.T) {
+	r := record{
+		"container": query.String("api-1"),
+		"status":    query.Number(503),
+		"code":      query.String("42"),
+		"msg":       query.String("GET /HealthCheck"),
+		"user":      query.String("bob"),
+		"latency":   query.String("abc"),
+	}
+	var tests = []struct {
+		s   string
+		exp bool
+	}{
+		{s: `status>=500`, exp: true},
+		{s: `status<1000`, exp: true},
+		{s: `status=503.0`, exp: true},
+		{s: `code>9`, exp: true},
+		{s: `user>alice`, exp: true},
+		{s: `user=bob && container=~"^api-\d+$"`, exp: true},
+		{s: `container!~"api"`, exp: false},
+		{s: `msg~"healthcheck"`, exp: true},
+		{s: `!msg~"healthcheck"`, exp: false},
+		{s: `user`, exp: true},
+		{s: `trace_id`, exp: false},
+		{s: `trace_id!=x`, exp: false},
+		{s: `!(trace_id=x)`, exp: true},
+		{s: `status<500 || user=bob`, exp: true},
+		{s: `latency>500`, exp: false},
+		{s: `latency<500`, exp: false},
+		{s: `latency!=500`, exp: true},
+		{s: `user>"1000"`, exp: false},
+	}
+	for i, tt := range tests {
+		expr, err := query.Parse(tt.s)
+		if err != nil {
+			t.Fatalf("%d. %q: %v", i, tt.s, err)
+		}
+		if got := expr.Eval(r); got != tt.exp {
+			t.Errorf("%d. %q: exp=%v got=%v", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// errstring returns the string representation of an error.
+func errstring(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
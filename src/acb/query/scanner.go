@@ -0,0 +1,201 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Based on https://github.com/benbjohnson/sql-parser/blob/master/scanner.go
+
+// Scanner represents a lexical scanner.
+type Scanner struct {
+	r   *bufio.Reader
+	pos int
+}
+
+// NewScanner returns a new instance of Scanner.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r)}
+}
+
+// Scan returns the next token, its literal value and the position (in runes,
+// starting at 1) at which it starts.
+func (s *Scanner) Scan() (tok Token, lit string, pos int) {
+	pos = s.pos + 1
+
+	// Read the next rune.
+	ch := s.read()
+
+	// If we see whitespace then consume all contiguous whitespace.
+	// If we see an ident character then consume as an ident or keyword.
+	if isWhitespace(ch) {
+		s.unread()
+		tok, lit = s.scanWhitespace()
+		return tok, lit, pos
+	} else if isIdent(ch) {
+		s.unread()
+		tok, lit = s.scanIdent()
+		return tok, lit, pos
+	} else if ch == '"' {
+		s.unread()
+		tok, lit = s.scanQuotedString()
+		return tok, lit, pos
+	}
+
+	// Otherwise read the individual, or pair of, characters.
+	switch ch {
+	case eof:
+		return EOF, "", pos
+	case '(':
+		return LPAREN, "(", pos
+	case ')':
+		return RPAREN, ")", pos
+	case '~':
+		return CONTAINS, "~", pos
+	case '=':
+		switch s.read() {
+		case '=':
+			return EQ, "==", pos
+		case '~':
+			return MATCH, "=~", pos
+		}
+		s.unread()
+		return EQ, "=", pos
+	case '!':
+		switch s.read() {
+		case '=':
+			return NEQ, "!=", pos
+		case '~':
+			return NOTMATCH, "!~", pos
+		}
+		s.unread()
+		return NOT, "!", pos
+	case '<':
+		if s.read() == '=' {
+			return LTE, "<=", pos
+		}
+		s.unread()
+		return LT, "<", pos
+	case '>':
+		if s.read() == '=' {
+			return GTE, ">=", pos
+		}
+		s.unread()
+		return GT, ">", pos
+	case '&':
+		if s.read() == '&' {
+			return AND, "&&", pos
+		}
+		s.unread()
+	case '|':
+		if s.read() == '|' {
+			return OR, "||", pos
+		}
+		s.unread()
+	}
+
+	return ILLEGAL, string(ch), pos
+}
+
+// scanWhitespace consumes the current rune and all contiguous whitespace.
+func (s *Scanner) scanWhitespace() (tok Token, lit string) {
+	var buf bytes.Buffer
+	buf.WriteRune(s.read())
+
+	for {
+		if ch := s.read(); ch == eof {
+			break
+		} else if !isWhitespace(ch) {
+			s.unread()
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return WS, buf.String()
+}
+
+// scanIdent consumes the current rune and all contiguous ident runes.
+func (s *Scanner) scanIdent() (tok Token, lit string) {
+	var buf bytes.Buffer
+	buf.WriteRune(s.read())
+
+	for {
+		if ch := s.read(); ch == eof {
+			break
+		} else if !isIdent(ch) {
+			s.unread()
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	// If the string matches a keyword then return that keyword.
+	if tok, ok := keywords[buf.String()]; ok {
+		return tok, buf.String()
+	}
+	return IDENT, buf.String()
+}
+
+// scanQuotedString consumes a double quoted string, in which a backslash
+// escapes the following character.
+func (s *Scanner) scanQuotedString() (tok Token, lit string) {
+	var buf bytes.Buffer
+	if s.read() != '"' {
+		panic("scanQuotedString called without a starting string")
+	}
+
+	for {
+		if ch := s.read(); ch == eof {
+			return ILLEGAL, `"` + buf.String()
+		} else if ch == '\\' {
+			ch = s.read()
+			if ch == eof {
+				return ILLEGAL, `"` + buf.String()
+			}
+			// keep the backslash of regexp escapes such as \d
+			if ch != '"' && ch != '\\' {
+				buf.WriteRune('\\')
+			}
+			buf.WriteRune(ch)
+		} else if ch == '"' {
+			return STRING, buf.String()
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+}
+
+// read reads the next rune from the bufferred reader.
+// Returns the rune(0) if an error occurs (or io.EOF is returned).
+func (s *Scanner) read() rune {
+	ch, _, err := s.r.ReadRune()
+	if err != nil {
+		return eof
+	}
+	s.pos++
+	return ch
+}
+
+// unread places the previously read rune back on the reader.
+func (s *Scanner) unread() {
+	if err := s.r.UnreadRune(); err == nil {
+		s.pos--
+	}
+}
+
+// isWhitespace returns true if the rune is a space, tab, or newline.
+func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' }
+
+// isIdent returns true if the rune can be part of a field name or an
+// unquoted value such as 500, warn, 1.5 or http.status_code.
+func isIdent(ch rune) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') ||
+		ch == '_' || ch == '.' || ch == '-' || ch == '/' || ch == '@' || ch == ':'
+}
+
+// eof represents a marker rune for the end of the reader.
+var eof = rune(0)
@@ -0,0 +1,63 @@
+package query_test
+
+import (
+	"acb/query"
+	"strings"
+	"testing"
+)
+
+// Ensure the scanner can scan tokens correctly.
+func TestScanner_Scan(t *testing.T) {
+	var tests = []struct {
+		s   string
+		tok query.Token
+		lit string
+	}{
+		// Special tokens (EOF, ILLEGAL, WS)
+		{s: ``, tok: query.EOF},
+		{s: `#`, tok: query.ILLEGAL, lit: `#`},
+		{s: `&`, tok: query.ILLEGAL, lit: `&`},
+		{s: ` `, tok: query.WS, lit: " "},
+		{s: "\t", tok: query.WS, lit: "\t"},
+
+		// Operators
+		{s: `&&`, tok: query.AND, lit: `&&`},
+		{s: `||`, tok: query.OR, lit: `||`},
+		{s: `!`, tok: query.NOT, lit: `!`},
+		{s: `=`, tok: query.EQ, lit: `=`},
+		{s: `==`, tok: query.EQ, lit: `==`},
+		{s: `!=`, tok: query.NEQ, lit: `!=`},
+		{s: `<`, tok: query.LT, lit: `<`},
+		{s: `<=`, tok: query.LTE, lit: `<=`},
+		{s: `>`, tok: query.GT, lit: `>`},
+		{s: `>=`, tok: query.GTE, lit: `>=`},
+		{s: `=~`, tok: query.MATCH, lit: `=~`},
+		{s: `!~`, tok: query.NOTMATCH, lit: `!~`},
+		{s: `~`, tok: query.CONTAINS, lit: `~`},
+		{s: `(`, tok: query.LPAREN, lit: `(`},
+		{s: `)`, tok: query.RPAREN, lit: `)`},
+
+		// Identifiers and keywords
+		{s: `http.status_code`, tok: query.IDENT, lit: `http.status_code`},
+		{s: `1.5`, tok: query.IDENT, lit: `1.5`},
+		{s: `and`, tok: query.AND, lit: `and`},
+		{s: `or`, tok: query.OR, lit: `or`},
+		{s: `not`, tok: query.NOT, lit: `not`},
+
+		// Strings
+		{s: `"a b"`, tok: query.STRING, lit: `a b`},
+		{s: `"\""`, tok: query.STRING, lit: `"`},
+		{s: `"\d+"`, tok: query.STRING, lit: `\d+`},
+		{s: `"abc`, tok: query.ILLEGAL, lit: `"abc`},
+	}
+
+	for i, tt := range tests {
+		s := query.NewScanner(strings.NewReader(tt.s))
+		tok, lit, _ := s.Scan()
+		if tt.tok != tok {
+			t.Errorf("%d. %q token mismatch: exp=%d got=%d <%q>", i, tt.s, tt.tok, tok, lit)
+		} else if tt.lit != lit {
+			t.Errorf("%d. %q literal mismatch: exp=%q got=%q", i, tt.s, tt.lit, lit)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package query
+
+// Token represents a lexical token.
+type Token int
+
+const (
+	// Special tokens
+	ILLEGAL Token = iota
+	EOF
+	WS
+
+	// Literals
+	IDENT
+	STRING
+
+	// Boolean operators
+	AND // && or and
+	OR  // || or or
+	NOT // ! or not
+
+	// Comparison operators
+	EQ       // = or ==
+	NEQ      // !=
+	LT       // <
+	LTE      // <=
+	GT       // >
+	GTE      // >=
+	MATCH    // =~
+	NOTMATCH // !~
+	CONTAINS // ~
+
+	// Misc characters
+	LPAREN // (
+	RPAREN // )
+)
+
+var tokens = map[Token]string{
+	ILLEGAL:  "ILLEGAL",
+	EOF:      "end of expression",
+	WS:       "whitespace",
+	IDENT:    "identifier",
+	STRING:   "string",
+	AND:      "&&",
+	OR:       "||",
+	NOT:      "!",
+	EQ:       "=",
+	NEQ:      "!=",
+	LT:       "<",
+	LTE:      "<=",
+	GT:       ">",
+	GTE:      ">=",
+	MATCH:    "=~",
+	NOTMATCH: "!~",
+	CONTAINS: "~",
+	LPAREN:   "(",
+	RPAREN:   ")",
+}
+
+// String returns the operator, or a description of the token.
+func (tok Token) String() string {
+	return tokens[tok]
+}
+
+// isComparison returns true if the token compares a field to a value.
+func (tok Token) isComparison() bool {
+	return tok >= EQ && tok <= CONTAINS
+}
+
+// keywords are identifiers which are boolean operators.
+var keywords = map[string]Token{
+	"and": AND,
+	"or":  OR,
+	"not": NOT,
+}
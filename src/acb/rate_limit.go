@@ -0,0 +1,99 @@
+package dockerlogs
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DefaultNoticeInterval is how often the number of logs dropped from a
+// container is reported.
+const DefaultNoticeInterval = 5 * time.Second
+
+// RateLimit limits the logs kept from each container, so that one which
+// floods its logs does not drown out the others. Logs of ERROR level and
+// above are always kept.
+type RateLimit struct {
+	// Rate is the number of logs per second kept from a container, 0 for no
+	// limit. Bursts of up to a second's worth, and at least one log, are
+	// allowed.
+	Rate float64
+	// Sample is the fraction of logs kept at random, 0 or 1 to keep them all.
+	Sample float64
+}
+
+// IsZero returns true if no logs are dropped.
+func (r RateLimit) IsZero() bool {
+	return r.Rate <= 0 && (r.Sample <= 0 || r.Sample >= 1)
+}
+
+// limiter applies a RateLimit to the logs of one container, using the time
+// they were received at.
+type limiter struct {
+	RateLimit
+	tokens  float64
+	last    time.Time
+	dropped int
+	noticed time.Time
+	random  func() float64
+}
+
+func newLimiter(r RateLimit) *limiter {
+	return &limiter{RateLimit: r, random: rand.Float64}
+}
+
+// allow returns true if the log, received at now, should be kept.
+func (l *limiter) allow(log *Log, now time.Time) bool {
+	burst := math.Max(l.Rate, 1)
+	if l.last.IsZero() {
+		l.tokens, l.noticed = burst, now
+	} else if l.Rate > 0 && now.After(l.last) {
+		l.tokens = math.Min(l.tokens+now.Sub(l.last).Seconds()*l.Rate, burst)
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+
+	if log != nil && log.Level >= ERROR {
+		return true
+	}
+	if l.Sample > 0 && l.Sample < 1 && l.random() >= l.Sample {
+		l.dropped++
+		return false
+	}
+	if l.Rate > 0 {
+		if l.tokens < 1 {
+			l.dropped++
+			return false
+		}
+		l.tokens--
+	}
+	return true
+}
+
+// notice returns the number of logs dropped since the last notice, at most
+// once every DefaultNoticeInterval unless force is set.
+func (l *limiter) notice(now time.Time, force bool) int {
+	if l.dropped == 0 || (!force && now.Sub(l.noticed) < DefaultNoticeInterval) {
+		return 0
+	}
+	n := l.dropped
+	l.dropped, l.noticed = 0, now
+	return n
+}
+
+// droppedLine reports the number of logs a limiter dropped.
+func droppedLine(n int, timestamp time.Time) logLine {
+	msg := fmt.Sprintf("dropped %d lines", n)
+	return logLine{
+		Timestamp: timestamp,
+		Stream:    Stderr,
+		Line:      msg,
+		Log: &Log{
+			Level:   WARNING,
+			Msg:     msg,
+			Context: KeyValues{{"dropped", IntValue(int64(n))}},
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package dockerlogs
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure logs beyond the rate are dropped, apart from errors, and counted in
+// the notices.
+func TestLimiter_Rate(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := newLimiter(RateLimit{Rate: 10})
+
+	kept, dropped := 0, 0
+	// 100 logs a second for 10 seconds, every 10th of which is an error
+	for i := 0; i < 1000; i++ {
+		log := &Log{Level: INFO}
+		if i%10 == 0 {
+			log.Level = ERROR
+		}
+		now := start.Add(time.Duration(i) * 10 * time.Millisecond)
+		if l.allow(log, now) {
+			kept++
+		} else if log.Level == ERROR {
+			t.Fatalf("expected errors to be kept")
+		}
+		dropped += l.notice(now, false)
+	}
+	dropped += l.notice(start.Add(10*time.Second), true)
+
+	// 100 errors plus 10 logs a second, and a burst of 10 at the start
+	if kept < 200 || kept > 210 {
+		t.Errorf("expected around 200 logs to be kept, got %d", kept)
+	}
+	if kept+dropped != 1000 {
+		t.Errorf("expected the dropped logs to be reported, got %d kept and %d dropped", kept, dropped)
+	}
+}
+
+// Ensure rates below one log a second keep logs too.
+func TestLimiter_SlowRate(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := newLimiter(RateLimit{Rate: 0.5})
+	kept := 0
+	for i := 0; i < 20; i++ {
+		if l.allow(&Log{Level: INFO}, start.Add(time.Duration(i)*time.Second)) {
+			kept++
+		}
+	}
+	// a log every other second, the first one included
+	if kept != 10 {
+		t.Errorf("expected 10 logs to be kept, got %d", kept)
+	}
+}
+
+// Ensure sampling keeps logs at random, apart from errors.
+func TestLimiter_Sample(t *testing.T) {
+	now := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := newLimiter(RateLimit{Sample: 0.5})
+	random := []float64{0.1, 0.7, 0.4, 0.9}
+	l.random = func() float64 {
+		r := random[0]
+		random = random[1:]
+		return r
+	}
+	for _, tc := range []struct {
+		level LogLevel
+		exp   bool
+	}{
+		{INFO, true}, {DEBUG, false}, {FATAL, true}, {INFO, true}, {WARNING, false},
+	} {
+		if got := l.allow(&Log{Level: tc.level}, now); got != tc.exp {
+			t.Errorf("%v: exp=%v got=%v", tc.level, tc.exp, got)
+		}
+	}
+	if n := l.notice(now, false); n != 0 {
+		t.Errorf("expected no notice before the interval, got %d", n)
+	}
+	if n := l.notice(now.Add(DefaultNoticeInterval), false); n != 2 {
+		t.Errorf("expected 2 dropped logs, got %d", n)
+	}
+}
+
+// Ensure the logs dropped are reported at each tick, without waiting for the
+// container to log again.
+func TestLimitLogs(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	lines := make(chan logLine)
+	tick := make(chan time.Time)
+	ch := make(chan logLine, 10)
+	go func() {
+		limitLogs(lines, newLimiter(RateLimit{Rate: 1}), tick, ch)
+		close(ch)
+	}()
+
+	for i := 0; i < 3; i++ {
+		lines <- logLine{Timestamp: start, Line: "flood", Log: &Log{Level: INFO}}
+	}
+	if line := <-ch; line.Line != "flood" {
+		t.Fatalf("expected the first line to be kept, got %q", line.Line)
+	}
+	tick <- start
+	if line := <-ch; line.Line != "dropped 2 lines" || !line.Timestamp.Equal(start) {
+		t.Errorf("expected 2 dropped lines to be reported, got %q at %s", line.Line, line.Timestamp)
+	}
+	close(lines)
+	if line, ok := <-ch; ok {
+		t.Errorf("expected nothing left to report, got %q", line.Line)
+	}
+}
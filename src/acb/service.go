@@ -0,0 +1,111 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Labels set by docker-compose and swarm on the containers they create
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	composeNumberLabel  = "com.docker.compose.container-number"
+	swarmServiceLabel   = "com.docker.swarm.service.name"
+	swarmSlotLabel      = "com.docker.swarm.task.slot"
+)
+
+// replicaNames match the names compose (project_web_1, project-web-1) and
+// swarm (web.1.0123456789abcdefghijklmno) give to replicas, for containers
+// without their labels. Other names ending in a number, such as redis-2, are
+// not taken to be replicas.
+var replicaNames = []*regexp.Regexp{
+	regexp.MustCompile(`^(.+_.+)_(\d+)$`),
+	regexp.MustCompile(`^(.+-.+)-(\d+)$`),
+	regexp.MustCompile(`^(.+)\.(\d+)\.[0-9a-z]{25}$`),
+}
+
+// NewSource returns the source of a container, finding its service and replica
+// number from its labels or, failing that, its name. The project is only taken
+// from the compose label, as names such as my_job_2 need not be compose ones.
+func NewSource(name, image string, labels map[string]string) Source {
+	src := Source{Name: name, Image: image, Service: name, Project: labels[composeProjectLabel]}
+	for _, l := range [][2]string{
+		{composeServiceLabel, composeNumberLabel},
+		{swarmServiceLabel, swarmSlotLabel},
+	} {
+		if service, ok := labels[l[0]]; ok {
+			src.Service = service
+			src.Replica, _ = strconv.Atoi(labels[l[1]])
+			return src
+		}
+	}
+	for _, re := range replicaNames {
+		if m := re.FindStringSubmatch(name); m != nil {
+			src.Service = m[1]
+			src.Replica, _ = strconv.Atoi(m[2])
+			break
+		}
+	}
+	return src
+}
+
+// hslColor converts a hue in degrees, saturation and lightness to a color.
+func hslColor(h, s, l float64) *ansi.Color {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return ansi.RGBColor(uint8((r+m)*255+0.5), uint8((g+m)*255+0.5), uint8((b+m)*255+0.5))
+}
+
+// ContainerColor returns the color of a container's name. The hue is a hash
+// of its service, so that replicas share it, while their lightness differs
+// slightly so they can still be told apart. Replicas are spread over the
+// lightness range by multiples of the golden ratio, so that no two of them
+// get the same lightness and consecutive ones are far apart.
+func ContainerColor(src Source) *ansi.Color {
+	service := src.Service
+	if service == "" {
+		service = src.Name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(service))
+	hue := float64(h.Sum32() % 360)
+
+	lightness := theme.ContainerLightness
+	if src.Replica > 1 {
+		step := 0.24 * math.Mod(float64(src.Replica-1)*0.618034, 1)
+		if lightness > 0.5 {
+			step = -step
+		}
+		lightness += step
+	}
+	return hslColor(hue, 0.7, lightness)
+}
+
+// paintContainer renders a container name using the theme's container style,
+// in the container's own color unless the style sets one.
+func paintContainer(src Source, s string) string {
+	style := theme.Container
+	if style.Fg == nil {
+		style.Fg = ContainerColor(src)
+	}
+	return paint(style, s)
+}
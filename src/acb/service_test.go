@@ -0,0 +1,75 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"math"
+	"testing"
+)
+
+// hue returns the hue of a color in degrees.
+func hue(c *ansi.Color) float64 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	var h float64
+	switch max {
+	case min:
+		return 0
+	case r:
+		h = math.Mod((g-b)/(max-min), 6)
+	case g:
+		h = (b-r)/(max-min) + 2
+	default:
+		h = (r-g)/(max-min) + 4
+	}
+	return math.Mod(h*60+360, 360)
+}
+
+// Ensure the service and replica of a container are found from its labels
+// or name.
+func TestNewSource(t *testing.T) {
+	var tests = []struct {
+		name    string
+		labels  map[string]string
+		service string
+		replica int
+	}{
+		{name: "postgres", service: "postgres"},
+		{name: "shop_web_1", service: "shop_web", replica: 1},
+		{name: "shop-web-12", service: "shop-web", replica: 12},
+		{name: "redis-2", service: "redis-2"},
+		{name: "web.2", service: "web.2"},
+		{name: "web.2.0123456789abcdefghijklmno", service: "web", replica: 2},
+		{name: "shop_web_3", labels: map[string]string{composeServiceLabel: "web", composeNumberLabel: "3"}, service: "web", replica: 3},
+		{name: "api.1.x", labels: map[string]string{swarmServiceLabel: "api", swarmSlotLabel: "1"}, service: "api", replica: 1},
+	}
+	for i, tt := range tests {
+		src := NewSource(tt.name, "", tt.labels)
+		if src.Service != tt.service || src.Replica != tt.replica {
+			t.Errorf("%d. %s: exp=%s/%d got=%s/%d", i, tt.name, tt.service, tt.replica, src.Service, src.Replica)
+		}
+	}
+}
+
+// Ensure replicas share a hue which differs between services.
+func TestContainerColor(t *testing.T) {
+	web1 := ContainerColor(NewSource("shop_web_1", "", nil))
+	if again := ContainerColor(NewSource("shop_web_1", "", nil)); *again != *web1 {
+		t.Errorf("expected a stable color, got %v and %v", web1, again)
+	}
+	web2 := ContainerColor(NewSource("shop_web_2", "", nil))
+	if *web2 == *web1 {
+		t.Errorf("expected replicas to differ in lightness")
+	}
+	for replica := 3; replica <= 8; replica++ {
+		c := ContainerColor(Source{Service: "shop_web", Replica: replica})
+		if *c == *web1 || *c == *web2 {
+			t.Errorf("expected replica %d to differ in lightness from replicas 1 and 2", replica)
+		}
+	}
+	if math.Abs(hue(web1)-hue(web2)) > 2 {
+		t.Errorf("expected replicas to share a hue, got %.0f and %.0f", hue(web1), hue(web2))
+	}
+	if db := ContainerColor(NewSource("shop_db_1", "", nil)); math.Abs(hue(db)-hue(web1)) <= 2 {
+		t.Errorf("expected services to differ in hue")
+	}
+}
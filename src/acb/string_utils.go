@@ -1,19 +1,39 @@
 package dockerlogs
 
-import "strings"
+import (
+	"acb/ansi"
+	"strings"
+)
 
+// PadLeft pads s with spaces up to a display width of l, ignoring any escape
+// sequences and counting wide characters as two cells.
 func PadLeft(s string, l int) string {
-	needed := l - len(s)
+	needed := l - ansi.Width(s)
 	if needed > 0 {
 		return strings.Repeat(" ", needed) + s
 	}
 	return s
 }
 
+// PadRight is like PadLeft, but pads on the right.
 func PadRight(s string, l int) string {
-	needed := l - len(s)
+	needed := l - ansi.Width(s)
 	if needed > 0 {
 		return s + strings.Repeat(" ", needed)
 	}
 	return s
 }
+
+// SplitList splits repeated, comma separated flag values such as
+// --hide-fields pid,hostname --hide-fields env into a single list.
+func SplitList(values []string) []string {
+	list := []string{}
+	for _, v := range values {
+		for _, x := range strings.Split(v, ",") {
+			if x = strings.TrimSpace(x); x != "" {
+				list = append(list, x)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,160 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateRecord is the data a --template is executed with.
+type TemplateRecord struct {
+	Container string
+	Service   string
+	// Timestamp is the time docker received the line.
+	Timestamp time.Time
+	// Time is the time logged by the application, or Timestamp if the line
+	// did not include one.
+	Time      time.Time
+	Stream    string
+	Level     LogLevel
+	LevelName string
+	Caller    string
+	Msg       string
+	// Fields holds the context fields by name, e.g. {{.Fields.request_id}};
+	// missing fields are empty.
+	Fields map[string]Value
+	// Context holds the same fields in the order they were logged.
+	Context KeyValues
+	Line    string
+	Log     *Log
+	Source  Source
+	// Repeats is the number of logs collapsed into this one, until
+	// LastTimestamp, see --collapse.
+	Repeats       int
+	LastTimestamp time.Time
+	// Hop is set for records of a --trace logged by another container than
+	// the record before; Latency is the time between them.
+	Hop     bool
+	Latency time.Duration
+}
+
+func newTemplateRecord(r *Record) *TemplateRecord {
+	fields := map[string]Value{}
+	for _, kv := range r.Log.Context {
+		fields[kv.Key] = kv.Value
+	}
+	return &TemplateRecord{
+		Container: r.Source.Name,
+		Service:   r.Source.Service,
+		Timestamp: r.Timestamp,
+		Time:      r.Time(),
+		Stream:    r.Stream,
+		Level:     r.Log.Level,
+		LevelName: r.Log.LevelName,
+		Caller:    r.Log.Caller,
+		Msg:       r.Log.Msg,
+		Fields:    fields,
+		Context:   r.Log.Context,
+		Line:      r.Line,
+		Log:       r.Log,
+		Source:    r.Source,
+
+		Repeats:       r.Repeats,
+		LastTimestamp: r.LastTimestamp,
+		Hop:           r.Hop,
+		Latency:       r.Latency,
+	}
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// TemplateFuncs are the helper functions available to --template, in
+// addition to the text/template builtins.
+var TemplateFuncs = template.FuncMap{
+	// color STYLE VALUE renders the value in a style such as "bold red" or
+	// "#ffffff on #ff0000", see ansi.ParseStyle
+	"color": func(spec string, v interface{}) (string, error) {
+		style, err := ansi.ParseStyle(spec)
+		if err != nil {
+			return "", err
+		}
+		return paint(style, toString(v)), nil
+	},
+	// name SOURCE renders the container name in its color, e.g. {{name .}}
+	"name": func(r *TemplateRecord) string {
+		return paintContainer(r.Source, r.Container)
+	},
+	// level LEVEL renders a level as the colored abbreviation used by text
+	"level": func(l LogLevel) string {
+		return LogLevelToColorString(l)
+	},
+	// pad N VALUE right aligns the value in N columns, padr left aligns it
+	"pad": func(n int, v interface{}) string {
+		return PadLeft(toString(v), n)
+	},
+	"padr": func(n int, v interface{}) string {
+		return PadRight(toString(v), n)
+	},
+	// trunc N VALUE shortens the value to N columns
+	"trunc": func(n int, v interface{}) string {
+		return ansi.Truncate(toString(v), n, "…")
+	},
+	// time LAYOUT TIME formats a time using a Go layout, ms and sec are
+	// shorthands for the time of day
+	"time": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"ms": func(t time.Time) string {
+		return t.Format("15:04:05.000")
+	},
+	"sec": func(t time.Time) string {
+		return t.Format("15:04:05")
+	},
+	// fields CONTEXT renders fields as colored key=value pairs
+	"fields": func(kvs KeyValues) string {
+		buf := []string{}
+		for _, kv := range kvs {
+			buf = append(buf, DefaultFormatter.formatField(kv.Key, kv.Value.String()))
+		}
+		return strings.Join(buf, " ")
+	},
+	// json VALUE encodes a value as json
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseTemplate parses a --template, which is executed once per record with
+// a TemplateRecord.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(TemplateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+// Write executes the template and ends the output with a newline.
+func (t *templateWriter) Write(r *Record) error {
+	var buf strings.Builder
+	if err := t.tmpl.Execute(&buf, newTemplateRecord(r)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(t.w, buf.String())
+	return err
+}
@@ -0,0 +1,44 @@
+package dockerlogs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// Ensure templates are executed with the record and the helper functions.
+func TestTemplateWriter(t *testing.T) {
+	r := &Record{
+		Source:    NewSource("web", "", nil),
+		Timestamp: time.Date(2017, 1, 1, 10, 0, 0, 123000000, time.UTC),
+		Line:      `{"level":"warn","msg":"slow request to /api","request_id":"r1","x-id":2}`,
+	}
+	r.Log = ParseLog(r.Line)
+
+	var tests = []struct {
+		tmpl string
+		exp  string
+	}{
+		{tmpl: `{{.Container}} {{.Time | ms}} {{.Level}} {{.Msg}}`, exp: `web 10:00:00.123 warning slow request to /api`},
+		{tmpl: `{{.Fields.request_id}}|{{.Fields.missing}}|{{index .Fields "x-id"}}`, exp: `r1||2`},
+		{tmpl: `{{pad 5 .Container}}|{{padr 5 .Container}}|{{trunc 8 .Msg}}`, exp: `  web|web  |slow re…`},
+		{tmpl: `{{time "2006-01-02" .Timestamp}} {{json .Fields}}`, exp: `2017-01-01 {"request_id":"r1","x-id":2}`},
+	}
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewRecordWriter(OutputText, &buf, OutputOptions{Template: tt.tmpl})
+		if err != nil {
+			t.Fatalf("%d. %v", i, err)
+		}
+		if err := w.Write(r); err != nil {
+			t.Fatalf("%d. %v", i, err)
+		}
+		if got := buf.String(); got != tt.exp+"\n" {
+			t.Errorf("%d. %s: exp=%q got=%q", i, tt.tmpl, tt.exp, got)
+		}
+	}
+
+	if _, err := ParseTemplate(`{{.Msg`); err == nil {
+		t.Errorf("expected parse error")
+	}
+}
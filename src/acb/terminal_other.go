@@ -0,0 +1,17 @@
+//go:build appengine || (!linux && !freebsd && !darwin && !dragonfly && !netbsd && !openbsd)
+// +build appengine !linux,!freebsd,!darwin,!dragonfly,!netbsd,!openbsd
+
+package dockerlogs
+
+import (
+	"os"
+	"strconv"
+)
+
+// TerminalWidth returns $COLUMNS, or 0 if it is not set.
+func TerminalWidth(f *os.File) int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+//go:build (!appengine && linux) || freebsd || darwin || dragonfly || netbsd || openbsd
+// +build !appengine,linux freebsd darwin dragonfly netbsd openbsd
+
+package dockerlogs
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// TerminalWidth returns the number of columns of the terminal f writes to,
+// or of $COLUMNS if set. It returns 0 if f is not a terminal.
+func TerminalWidth(f *os.File) int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+
+	var dimensions [4]uint16
+	if _, _, err := syscall.Syscall6(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&dimensions)),
+		0, 0, 0,
+	); err == 0 {
+		return int(dimensions[1])
+	}
+	return 0
+}
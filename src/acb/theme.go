@@ -0,0 +1,323 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Theme holds the styles used to render logs on a terminal.
+type Theme struct {
+	Name      string
+	Levels    map[LogLevel]ansi.Style
+	Container ansi.Style
+	Timestamp ansi.Style
+	Caller    ansi.Style
+	Msg       ansi.Style
+	Key       ansi.Style
+	Separator ansi.Style
+	Value     ansi.Style
+	// Match marks the matches of --grep.
+	Match ansi.Style
+	// Highlights are given in turn to the --highlight rules.
+	Highlights []ansi.Style
+	// ContainerLightness is the lightness, from 0 to 1, of the colors given
+	// to container names when the Container style has no color.
+	ContainerLightness float64
+	// ANSI replaces the 8 basic colors of container output in --ansi theme
+	// mode, so that e.g. red output from npm matches our errors.
+	ANSI [8]*ansi.Color
+}
+
+func rgb(r, g, b uint8) *ansi.Color {
+	return ansi.RGBColor(r, g, b)
+}
+
+// DarkTheme is meant for light text on a dark background.
+var DarkTheme = &Theme{
+	Name: "dark",
+	Levels: map[LogLevel]ansi.Style{
+		FATAL:    {Bg: rgb(255, 0, 0)},
+		ALERT:    {Bg: rgb(255, 0, 0)},
+		CRITICAL: {Bg: rgb(255, 0, 0)},
+		ERROR:    {Fg: rgb(255, 0, 0)},
+		WARNING:  {Fg: rgb(255, 245, 32)},
+		NOTICE:   {Fg: rgb(20, 190, 60)},
+		INFO:     {Fg: rgb(20, 172, 190)},
+		DEBUG:    {Fg: rgb(221, 28, 119)},
+		TRACE:    {Fg: rgb(120, 120, 120)},
+		UNKNOWN:  {Fg: rgb(221, 28, 119)},
+	},
+	Caller:    ansi.Style{Fg: rgb(120, 120, 120)},
+	Msg:       ansi.Style{Fg: rgb(255, 255, 255)},
+	Key:       ansi.Style{Fg: rgb(0, 100, 90)},
+	Separator: ansi.Style{Fg: rgb(190, 190, 190)},
+	Value:     ansi.Style{Fg: rgb(120, 120, 120)},
+	Match:     ansi.Style{Fg: rgb(0, 0, 0), Bg: rgb(255, 245, 32)},
+	Highlights: []ansi.Style{
+		{Fg: rgb(0, 0, 0), Bg: rgb(95, 215, 255)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(255, 135, 215)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(135, 255, 135)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(255, 175, 95)},
+	},
+
+	ContainerLightness: 0.65,
+	ANSI:               ansiPalette,
+}
+
+// LightTheme is meant for dark text on a light background, such as
+// Solarized light.
+var LightTheme = &Theme{
+	Name: "light",
+	Levels: map[LogLevel]ansi.Style{
+		FATAL:    {Fg: rgb(255, 255, 255), Bg: rgb(215, 0, 0)},
+		ALERT:    {Fg: rgb(255, 255, 255), Bg: rgb(215, 0, 0)},
+		CRITICAL: {Fg: rgb(255, 255, 255), Bg: rgb(215, 0, 0)},
+		ERROR:    {Fg: rgb(215, 0, 0)},
+		WARNING:  {Fg: rgb(175, 135, 0)},
+		NOTICE:   {Fg: rgb(0, 135, 0)},
+		INFO:     {Fg: rgb(0, 135, 175)},
+		DEBUG:    {Fg: rgb(175, 0, 95)},
+		TRACE:    {Fg: rgb(128, 128, 128)},
+		UNKNOWN:  {Fg: rgb(175, 0, 95)},
+	},
+	Caller:    ansi.Style{Fg: rgb(108, 108, 108)},
+	Msg:       ansi.Style{Fg: rgb(28, 28, 28)},
+	Key:       ansi.Style{Fg: rgb(0, 95, 135)},
+	Separator: ansi.Style{Fg: rgb(138, 138, 138)},
+	Value:     ansi.Style{Fg: rgb(88, 88, 88)},
+	Match:     ansi.Style{Fg: rgb(0, 0, 0), Bg: rgb(255, 215, 95)},
+	Highlights: []ansi.Style{
+		{Fg: rgb(0, 0, 0), Bg: rgb(175, 215, 255)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(255, 175, 215)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(175, 255, 175)},
+		{Fg: rgb(0, 0, 0), Bg: rgb(255, 215, 175)},
+	},
+
+	ContainerLightness: 0.35,
+	ANSI: [8]*ansi.Color{
+		rgb(128, 128, 128), // black
+		rgb(215, 0, 0),     // red
+		rgb(0, 135, 0),     // green
+		rgb(175, 135, 0),   // yellow
+		rgb(0, 95, 175),    // blue
+		rgb(175, 0, 95),    // magenta
+		rgb(0, 135, 175),   // cyan
+		rgb(28, 28, 28),    // white
+	},
+}
+
+var builtinThemes = []*Theme{DarkTheme, LightTheme}
+
+// Themes holds the built in themes and those loaded by LoadThemes, by name.
+var Themes = map[string]*Theme{
+	DarkTheme.Name:  DarkTheme,
+	LightTheme.Name: LightTheme,
+}
+
+var theme = DarkTheme
+
+// SetColors sets the theme used to render logs, and the color depth of the
+// terminal they are written to; ansi.NoColor disables colors.
+func SetColors(t *Theme, depth ansi.Depth) {
+	theme = t
+	colorDepth = depth
+}
+
+// Color modes, as given to --color
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+var ColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// termColorDepth guesses the color depth of a terminal from $TERM and
+// $COLORTERM.
+func termColorDepth(term, colorterm string) ansi.Depth {
+	switch {
+	case colorterm == "truecolor" || colorterm == "24bit":
+		return ansi.TrueColor
+	case strings.Contains(term, "256color"):
+		return ansi.Color256
+	case term == "dumb":
+		return ansi.NoColor
+	default:
+		return ansi.Color16
+	}
+}
+
+// DetectColorDepth returns the color depth to use when writing to f. In
+// auto mode colors are disabled if f is not a terminal or $NO_COLOR is set.
+func DetectColorDepth(mode string, f *os.File) ansi.Depth {
+	depth := termColorDepth(os.Getenv("TERM"), os.Getenv("COLORTERM"))
+	switch mode {
+	case ColorNever:
+		return ansi.NoColor
+	case ColorAlways:
+		if depth == ansi.NoColor {
+			return ansi.Color16
+		}
+		return depth
+	}
+	if os.Getenv("NO_COLOR") != "" || !isTerminal(f) {
+		return ansi.NoColor
+	}
+	return depth
+}
+
+type themeConfig struct {
+	// Base is the built in theme which unset styles are taken from, dark by
+	// default.
+	Base      string            `json:"base"`
+	Levels    map[string]string `json:"levels"`
+	Container string            `json:"container"`
+	Timestamp string            `json:"timestamp"`
+	Caller    string            `json:"caller"`
+	Msg       string            `json:"msg"`
+	Key       string            `json:"key"`
+	Separator string            `json:"separator"`
+	Value     string            `json:"value"`
+	Match     string            `json:"match"`
+	// Highlights replaces the base theme's highlight styles if set
+	Highlights []string `json:"highlights"`
+	// ContainerLightness is only used if set, i.e. above 0
+	ContainerLightness float64  `json:"container_lightness"`
+	ANSI               []string `json:"ansi"`
+}
+
+func DefaultThemesPath() string {
+	return filepath.Join(ConfigDir(), "themes.json")
+}
+
+func (c *themeConfig) theme(name string) (*Theme, error) {
+	if c.Base == "" {
+		c.Base = DarkTheme.Name
+	}
+	var base *Theme
+	for _, t := range builtinThemes {
+		if t.Name == c.Base {
+			base = t
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("theme %s: unknown base theme %q", name, c.Base)
+	}
+	t := *base
+	t.Name = name
+	t.Levels = map[LogLevel]ansi.Style{}
+	for l, s := range base.Levels {
+		t.Levels[l] = s
+	}
+
+	for _, x := range []struct {
+		spec  string
+		style *ansi.Style
+	}{
+		{c.Container, &t.Container},
+		{c.Timestamp, &t.Timestamp},
+		{c.Caller, &t.Caller},
+		{c.Msg, &t.Msg},
+		{c.Key, &t.Key},
+		{c.Separator, &t.Separator},
+		{c.Value, &t.Value},
+		{c.Match, &t.Match},
+	} {
+		if x.spec == "" {
+			continue
+		}
+		style, err := ansi.ParseStyle(x.spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %v", name, err)
+		}
+		*x.style = style
+	}
+
+	if c.Highlights != nil {
+		t.Highlights = nil
+		for _, spec := range c.Highlights {
+			style, err := ansi.ParseStyle(spec)
+			if err != nil {
+				return nil, fmt.Errorf("theme %s: %v", name, err)
+			}
+			t.Highlights = append(t.Highlights, style)
+		}
+	}
+
+	if c.ContainerLightness < 0 || c.ContainerLightness > 1 {
+		return nil, fmt.Errorf("theme %s: container_lightness must be between 0 and 1", name)
+	}
+	if c.ContainerLightness > 0 {
+		t.ContainerLightness = c.ContainerLightness
+	}
+
+	for l, spec := range c.Levels {
+		level, err := ParseLogLevel(l)
+		if err != nil && strings.ToLower(l) != UNKNOWN.String() {
+			return nil, fmt.Errorf("theme %s: %v", name, err)
+		}
+		style, err := ansi.ParseStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %v", name, err)
+		}
+		t.Levels[level] = style
+	}
+
+	if c.ANSI != nil && len(c.ANSI) != len(t.ANSI) {
+		return nil, fmt.Errorf("theme %s: expected %d ansi colors", name, len(t.ANSI))
+	}
+	for i, spec := range c.ANSI {
+		color, err := ansi.ParseColor(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %v", name, err)
+		}
+		t.ANSI[i] = color
+	}
+	return &t, nil
+}
+
+// LoadThemes adds the themes defined in a json file such as
+// ~/.config/dockerlogs/themes.json to Themes, e.g.
+//
+//	{"solarized": {
+//	    "base": "light",
+//	    "msg": "#073642",
+//	    "key": "bold cyan",
+//	    "levels": {"warning": "#b58900", "fatal": "#fdf6e3 on #dc322f"}
+//	}}
+//
+// Styles are space separated colors and attributes, see ansi.ParseStyle;
+// those which are not given are taken from the base theme. A missing file is
+// not an error.
+func LoadThemes(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	configs := map[string]*themeConfig{}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for name, c := range configs {
+		t, err := c.theme(name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		Themes[name] = t
+	}
+	return nil
+}
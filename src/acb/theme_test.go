@@ -0,0 +1,74 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Ensure themes are loaded on top of their base theme.
+func TestLoadThemes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "themes.json")
+	config := `{"solarized": {"base": "light", "msg": "#073642", "levels": {"warn": "bold yellow"}}}`
+	if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadThemes(filename); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Themes, "solarized")
+
+	theme := Themes["solarized"]
+	if theme == nil {
+		t.Fatalf("expected solarized theme")
+	}
+	if exp := ansi.RGBColor(7, 54, 66); *theme.Msg.Fg != *exp {
+		t.Errorf("expected msg color %v, got %v", exp, theme.Msg.Fg)
+	}
+	if style := theme.Levels[WARNING]; !style.Bold || style.Fg.Index != 3 {
+		t.Errorf("expected bold yellow warnings, got %#v", style)
+	}
+	if theme.Levels[ERROR].Fg != LightTheme.Levels[ERROR].Fg || theme.Key.Fg != LightTheme.Key.Fg {
+		t.Errorf("expected unset styles to be taken from the light theme")
+	}
+
+	for _, config := range []string{
+		`{"x": {"base": "solarized"}}`,
+		`{"x": {"msg": "pink"}}`,
+		`{"x": {"levels": {"loud": "red"}}}`,
+		`{"x": {"ansi": ["red"]}}`,
+	} {
+		if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := LoadThemes(filename); err == nil {
+			t.Errorf("%s: expected error", config)
+		}
+	}
+}
+
+// Ensure the color depth is guessed from the terminal type.
+func TestTermColorDepth(t *testing.T) {
+	var tests = []struct {
+		term, colorterm string
+		exp             ansi.Depth
+	}{
+		{term: "xterm-256color", colorterm: "truecolor", exp: ansi.TrueColor},
+		{term: "screen-256color", exp: ansi.Color256},
+		{term: "xterm", exp: ansi.Color16},
+		{term: "dumb", exp: ansi.NoColor},
+	}
+	for i, tt := range tests {
+		if got := termColorDepth(tt.term, tt.colorterm); got != tt.exp {
+			t.Errorf("%d. %s: exp=%d got=%d", i, tt.term, tt.exp, got)
+		}
+	}
+}
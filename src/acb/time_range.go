@@ -0,0 +1,160 @@
+package dockerlogs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var logTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// minLogTime is the earliest unix time, in seconds, taken for the time of a
+// log; smaller numbers, such as counters or durations, are not times.
+const minLogTime = 946684800 // 2000-01-01T00:00:00Z
+
+// parseLogTime parses the time logged by an application, as an RFC 3339 or
+// similar time, or as a unix time in seconds, milliseconds, microseconds or
+// nanoseconds after 2000.
+func parseLogTime(v Value) (time.Time, bool) {
+	s := v.String()
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// units per second, each covering times from 2000 to the 2900s
+		for _, per := range []int64{1, 1e3, 1e6, 1e9} {
+			if min := minLogTime * per; n >= min && n/100 < min {
+				return time.Unix(n/per, n%per*(1e9/per)), true
+			}
+		}
+		return time.Time{}, false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= minLogTime && f < minLogTime*100 {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+	for _, layout := range logTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// timeBound is a --from or --to time: either a point in time, or a time of
+// day. It is given to a resolution, such as a second for 10:42:00.
+type timeBound struct {
+	t     time.Time
+	clock bool
+	res   time.Duration
+}
+
+var boundLayouts = []struct {
+	layout string
+	clock  bool
+	res    time.Duration
+}{
+	{time.RFC3339Nano, false, 0},
+	{"2006-01-02T15:04:05.999999999", false, 0},
+	{"2006-01-02 15:04:05.999999999", false, 0},
+	{"2006-01-02T15:04", false, time.Minute},
+	{"2006-01-02 15:04", false, time.Minute},
+	{"2006-01-02", false, 24 * time.Hour},
+	{"15:04:05.999999999", true, 0},
+	{"15:04", true, time.Minute},
+}
+
+// secondsResolution returns the resolution of a time given to the second or
+// a fraction of it, e.g. a millisecond for 10:42:00.125.
+func secondsResolution(s string) time.Duration {
+	i := strings.LastIndexByte(s, '.')
+	if i == -1 {
+		return time.Second
+	}
+	digits := 0
+	for _, c := range s[i+1:] {
+		if c < '0' || c > '9' {
+			break
+		}
+		digits++
+	}
+	return time.Duration(math.Pow10(9 - digits))
+}
+
+func parseTimeBound(s string, loc *time.Location) (timeBound, error) {
+	for _, l := range boundLayouts {
+		t, err := time.ParseInLocation(l.layout, s, loc)
+		if err != nil {
+			continue
+		}
+		res := l.res
+		if res == 0 {
+			res = secondsResolution(s)
+		}
+		return timeBound{t, l.clock, res}, nil
+	}
+	return timeBound{}, fmt.Errorf("invalid time %q, expected e.g. 10:42:00 or 2017-01-01T10:42:00", s)
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// since returns the time from the bound to t, which is compared by its time
+// of day in loc if the bound is a time of day.
+func (b *timeBound) since(t time.Time, loc *time.Location) time.Duration {
+	if b.clock {
+		return timeOfDay(t.In(loc)) - timeOfDay(b.t)
+	}
+	return t.Sub(b.t)
+}
+
+// TimeRange selects the records logged between two times, by the time logged
+// by the application if there is one, or else the time docker received them.
+type TimeRange struct {
+	from, to *timeBound
+	loc      *time.Location
+}
+
+// ParseTimeRange parses --from and --to, either of which may be empty. Times
+// of day, such as 10:42:00, match every day; those without a time zone are
+// taken to be in loc. The range includes the whole of its last unit, i.e.
+// --to 10:45:30 includes 10:45:30.5.
+func ParseTimeRange(from, to string, loc *time.Location) (*TimeRange, error) {
+	tr := &TimeRange{loc: loc}
+	for _, x := range []struct {
+		s     string
+		bound **timeBound
+	}{{from, &tr.from}, {to, &tr.to}} {
+		if x.s == "" {
+			continue
+		}
+		b, err := parseTimeBound(x.s, loc)
+		if err != nil {
+			return nil, err
+		}
+		*x.bound = &b
+	}
+	return tr, nil
+}
+
+// Match returns true if the record was logged within the range.
+func (tr *TimeRange) Match(r *Record) bool {
+	t := r.Time()
+	if t.IsZero() {
+		return false
+	}
+	after := tr.from == nil || tr.from.since(t, tr.loc) >= 0
+	before := tr.to == nil || tr.to.since(t, tr.loc) < tr.to.res
+	if tr.from != nil && tr.to != nil && tr.from.clock && tr.to.clock && timeOfDay(tr.from.t) > timeOfDay(tr.to.t) {
+		// the range spans midnight, e.g. 23:00 to 01:00
+		return after || before
+	}
+	return after && before
+}
@@ -0,0 +1,79 @@
+package dockerlogs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogTime(t *testing.T) {
+	exp := time.Date(2017, 1, 1, 10, 42, 0, 125000000, time.UTC)
+	for _, v := range []Value{
+		StringValue("2017-01-01T10:42:00.125Z"),
+		StringValue("2017-01-01T11:42:00.125+01:00"),
+		StringValue("2017-01-01 10:42:00.125"),
+		FloatValue(1483267320.125),
+		IntValue(1483267320125),
+		IntValue(1483267320125000),
+		IntValue(1483267320125000000),
+		StringValue("1483267320125"),
+	} {
+		got, ok := parseLogTime(v)
+		if !ok || !got.Equal(exp) {
+			t.Errorf("%v: exp=%v got=%v", v, exp, got)
+		}
+	}
+	for _, v := range []Value{
+		StringValue("yesterday"), IntValue(0), StringValue(""), IntValue(42), IntValue(-1483267320),
+		FloatValue(3.5), IntValue(123456789012), StringValue("99999999999999"),
+	} {
+		if _, ok := parseLogTime(v); ok {
+			t.Errorf("%v: expected no time", v)
+		}
+	}
+}
+
+// Ensure logs are selected by their time, or their time of day, and that the
+// range includes the whole of its last unit.
+func TestTimeRange_Match(t *testing.T) {
+	at := func(s string) *Record {
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &Record{Log: &Log{Time: ts}}
+	}
+	for _, tc := range []struct {
+		from, to string
+		record   *Record
+		exp      bool
+	}{
+		{"10:42:00", "10:45:30", at("2017-01-01T10:41:59.999Z"), false},
+		{"10:42:00", "10:45:30", at("2017-01-01T10:42:00Z"), true},
+		{"10:42:00", "10:45:30", at("2017-01-02T10:45:30.999Z"), true},
+		{"10:42:00", "10:45:30", at("2017-01-01T10:45:31Z"), false},
+		{"10:42:00", "10:45:30", at("2017-01-01T12:43:00+02:00"), true},
+		{"10:42", "10:45", at("2017-01-01T10:45:59Z"), true},
+		{"10:42:00.5", "", at("2017-01-01T10:42:00.4Z"), false},
+		{"", "10:42:00.5", at("2017-01-01T10:42:00.55Z"), true},
+		{"", "10:42:00.5", at("2017-01-01T10:42:00.6Z"), false},
+		{"23:00", "01:00", at("2017-01-01T00:30:00Z"), true},
+		{"23:00", "01:00", at("2017-01-01T12:00:00Z"), false},
+		{"2017-01-01T10:42:00Z", "", at("2017-01-02T09:00:00Z"), true},
+		{"2017-01-01", "2017-01-01", at("2017-01-01T23:59:59Z"), true},
+		{"2017-01-01", "2017-01-01", at("2017-01-02T00:00:00Z"), false},
+		{"10:42", "", &Record{Log: &Log{}}, false},
+		{"10:42", "", &Record{Timestamp: at("2017-01-01T10:43:00Z").Log.Time, Log: &Log{}}, true},
+	} {
+		tr, err := ParseTimeRange(tc.from, tc.to, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tr.Match(tc.record); got != tc.exp {
+			t.Errorf("%s-%s %v: exp=%v got=%v", tc.from, tc.to, tc.record.Time(), tc.exp, got)
+		}
+	}
+
+	if _, err := ParseTimeRange("10h42", "", time.UTC); err == nil {
+		t.Errorf("expected an invalid time error")
+	}
+}
@@ -0,0 +1,89 @@
+package dockerlogs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timestamp precisions, as given to --precision
+var Precisions = []string{"s", "ms", "us", "ns"}
+
+var precisionUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// ParsePrecision returns the unit of a precision such as ms.
+func ParsePrecision(s string) (time.Duration, error) {
+	unit, ok := precisionUnits[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown precision %q, expected one of %s", s, strings.Join(Precisions, ", "))
+	}
+	return unit, nil
+}
+
+// ParseTimeZone returns the zone given to --tz: local, UTC or an IANA name
+// such as Europe/Paris.
+func ParseTimeZone(s string) (*time.Location, error) {
+	switch strings.ToLower(s) {
+	case "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s)
+}
+
+// TimestampLayout returns the layout of the timestamps shown by the text
+// output, to a precision (0 for seconds); compact layouts only show the time
+// of day.
+func TimestampLayout(precision time.Duration, compact bool) string {
+	layout := "15:04:05"
+	switch {
+	case precision <= 0 || precision >= time.Second:
+	case precision < time.Microsecond:
+		layout += ".000000000"
+	case precision < time.Millisecond:
+		layout += ".000000"
+	case precision < time.Second:
+		layout += ".000"
+	}
+	if !compact {
+		layout = "2006-01-02T" + layout
+	}
+	return layout
+}
+
+type timeWriter struct {
+	w         RecordWriter
+	loc       *time.Location
+	precision time.Duration
+}
+
+func (t *timeWriter) convert(ts time.Time) time.Time {
+	if ts.IsZero() {
+		return ts
+	}
+	if t.loc != nil {
+		ts = ts.In(t.loc)
+	}
+	if t.precision > 0 {
+		ts = ts.Truncate(t.precision)
+	}
+	return ts
+}
+
+// Write writes a copy of the record with its times in the zone, and
+// truncated to the precision.
+func (t *timeWriter) Write(r *Record) error {
+	l := *r.Log
+	l.Time = t.convert(l.Time)
+	converted := *r
+	converted.Log = &l
+	converted.Timestamp = t.convert(r.Timestamp)
+	converted.LastTimestamp = t.convert(r.LastTimestamp)
+	return t.w.Write(&converted)
+}
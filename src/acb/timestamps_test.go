@@ -0,0 +1,90 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTimestampLayout(t *testing.T) {
+	ts := time.Date(2017, 1, 1, 10, 42, 0, 123456789, time.UTC)
+	for _, tc := range []struct {
+		precision string
+		compact   bool
+		exp       string
+	}{
+		{"s", false, "2017-01-01T10:42:00"},
+		{"ms", false, "2017-01-01T10:42:00.123"},
+		{"us", true, "10:42:00.123456"},
+		{"ns", true, "10:42:00.123456789"},
+	} {
+		unit, err := ParsePrecision(tc.precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ts.Format(TimestampLayout(unit, tc.compact)); got != tc.exp {
+			t.Errorf("%s %v: exp=%q got=%q", tc.precision, tc.compact, tc.exp, got)
+		}
+	}
+	if _, err := ParsePrecision("min"); err == nil {
+		t.Errorf("expected an unknown precision error")
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		exp *time.Location
+	}{
+		{"local", time.Local},
+		{"UTC", time.UTC},
+		{"utc", time.UTC},
+	} {
+		if got, err := ParseTimeZone(tc.s); err != nil || got != tc.exp {
+			t.Errorf("%s: exp=%v got=%v (%v)", tc.s, tc.exp, got, err)
+		}
+	}
+	if _, err := ParseTimeZone("Nowhere/Special"); err == nil {
+		t.Errorf("expected an unknown time zone error")
+	}
+}
+
+// Ensure the zone and precision apply to every time, in every format.
+func TestRecordWriter_TimeZone(t *testing.T) {
+	defer SetColors(DarkTheme, ansi.TrueColor)
+	SetColors(DarkTheme, ansi.NoColor)
+	loc := time.FixedZone("CET", 3600)
+	r := &Record{
+		Source:    NewSource("web", "", nil),
+		Timestamp: time.Date(2017, 1, 1, 10, 42, 0, 123456789, time.UTC),
+		Line:      "ready",
+		Log:       &Log{Level: INFO, Msg: "ready", Time: time.Date(2017, 1, 1, 10, 41, 59, 987654321, time.UTC)},
+	}
+	for _, tc := range []struct {
+		format string
+		opts   OutputOptions
+		exp    string
+	}{
+		{OutputText, OutputOptions{TimestampLayout: TimestampLayout(time.Millisecond, true)}, "11:42:00.123 INF ready\n"},
+		{OutputText, OutputOptions{Template: "{{.Time | ms}}"}, "11:41:59.987\n"},
+		{OutputLogfmt, OutputOptions{}, "container=web timestamp=2017-01-01T11:42:00.123+01:00 time=2017-01-01T11:41:59.987+01:00 level=info msg=ready\n"},
+		{OutputRaw, OutputOptions{}, "ready\n"},
+	} {
+		tc.opts.Location, tc.opts.Precision = loc, time.Millisecond
+		var buf bytes.Buffer
+		w, err := NewRecordWriter(tc.format, &buf, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tc.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.format, tc.exp, buf.String())
+		}
+	}
+	if r.Timestamp.Location() != time.UTC {
+		t.Errorf("expected the record not to be modified")
+	}
+}
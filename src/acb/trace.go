@@ -0,0 +1,122 @@
+package dockerlogs
+
+import "time"
+
+// DefaultTraceFields are the fields whose ids are traced when following
+// linked records.
+var DefaultTraceFields = []string{"request_id", "trace_id", "span_id"}
+
+// Trace selects the records of a request from every container, by the ids
+// their context contains.
+type Trace struct {
+	// Follow traces the ids of the records found too, i.e. the values of
+	// Fields and of ParentField, so that a record whose parent_id is the
+	// span_id of a traced record is traced as well. Followed ids are only
+	// looked for in those fields, unlike the ids given.
+	Follow      bool
+	Fields      []string
+	ParentField string
+
+	ids      map[string]bool
+	followed map[string]bool
+	last     *Record
+}
+
+// NewTrace returns a trace of the records containing the ids.
+func NewTrace(ids ...string) *Trace {
+	t := &Trace{
+		Fields:      DefaultTraceFields,
+		ParentField: "parent_id",
+		ids:         map[string]bool{},
+		followed:    map[string]bool{},
+	}
+	for _, id := range ids {
+		t.ids[id] = true
+	}
+	return t
+}
+
+// contains returns true if any of the values, or the values nested in them,
+// is a traced id.
+func (t *Trace) contains(values []Value) bool {
+	for _, v := range values {
+		switch v.Kind {
+		case ObjectKind:
+			nested := []Value{}
+			for _, kv := range v.Object {
+				nested = append(nested, kv.Value)
+			}
+			if t.contains(nested) {
+				return true
+			}
+		case ArrayKind:
+			if t.contains(v.Array) {
+				return true
+			}
+		case NullKind:
+		default:
+			if t.ids[v.String()] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// idFields returns the values of Fields and ParentField in the context.
+func (t *Trace) idFields(context KeyValues) []string {
+	ids := []string{}
+	for _, name := range append([]string{t.ParentField}, t.Fields...) {
+		if v, ok := lookupField(context, name); ok && v.Kind != ObjectKind && v.Kind != ArrayKind && v.String() != "" {
+			ids = append(ids, v.String())
+		}
+	}
+	return ids
+}
+
+// Add returns true if r is part of the trace. Records which are part of it
+// are expected in the order of their Timestamp, or of their Time if they
+// have none; those from another container than the one before are marked as
+// a Hop, with the latency since that one.
+func (t *Trace) Add(r *Record) bool {
+	values := []Value{}
+	for _, kv := range r.Log.Context {
+		values = append(values, kv.Value)
+	}
+	found := t.contains(values)
+	ids := t.idFields(r.Log.Context)
+	for _, id := range ids {
+		if t.followed[id] {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if t.Follow {
+		for _, id := range ids {
+			t.followed[id] = true
+		}
+	}
+	if t.last != nil && t.last.Source.Name != r.Source.Name {
+		r.Hop = true
+		r.Latency = latency(t.last, r)
+	}
+	t.last = r
+	return true
+}
+
+// latency returns the time between two records by the timestamps docker
+// received them at, which they are ordered by, or else by the times logged,
+// and 0 if they are out of order.
+func latency(from, to *Record) time.Duration {
+	d := to.Time().Sub(from.Time())
+	if !from.Timestamp.IsZero() && !to.Timestamp.IsZero() {
+		d = to.Timestamp.Sub(from.Timestamp)
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,86 @@
+package dockerlogs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Ensure records containing a traced id are selected, following linked ids
+// if asked to, and hops between containers get their latency.
+func TestTrace_Add(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{Source: Source{Name: "web"}, Line: `{"msg":"request","request_id":"r1","span_id":"s1"}`},
+		{Source: Source{Name: "web"}, Line: `{"msg":"other request","request_id":"r2","span_id":"s9"}`},
+		{Source: Source{Name: "api"}, Line: `{"msg":"lookup","parent_id":"s1","span_id":"s2"}`},
+		{Source: Source{Name: "db"}, Line: `{"msg":"query","parent_id":"s2","args":[1,"r1"]}`},
+		{Source: Source{Name: "db"}, Line: `{"msg":"nested","http":{"request_id":"r1"}}`},
+		{Source: Source{Name: "db"}, Line: `{"msg":"unrelated","user":"s2"}`},
+		{Source: Source{Name: "web"}, Line: `{"msg":"response","request_id":"r1","status":200}`},
+	}
+	for i, r := range records {
+		r.Timestamp = start.Add(time.Duration(i) * 10 * time.Millisecond)
+		r.Log = ParseLog(r.Line)
+	}
+
+	for _, tc := range []struct {
+		follow bool
+		exp    []string
+	}{
+		{false, []string{"request", "query", "nested", "response"}},
+		{true, []string{"request", "lookup", "query", "nested", "response"}},
+	} {
+		trace := NewTrace("r1")
+		trace.Follow = tc.follow
+		got := []string{}
+		for _, r := range records {
+			r.Hop, r.Latency = false, 0
+			if trace.Add(r) {
+				got = append(got, r.Log.Msg)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("follow=%v: exp=%q got=%q", tc.follow, tc.exp, got)
+		}
+	}
+
+	// the last run followed the trace through api and db back to web
+	for i, exp := range []time.Duration{0, 0, 20 * time.Millisecond, 10 * time.Millisecond, 0, 0, 20 * time.Millisecond} {
+		if records[i].Latency != exp || records[i].Hop != (exp != 0) {
+			t.Errorf("%s: expected latency %v, got %v", records[i].Log.Msg, exp, records[i].Latency)
+		}
+	}
+}
+
+// Ensure latencies are measured by the timestamps records are ordered by,
+// rather than the times logged by containers whose clocks differ, and are
+// never negative.
+func TestTrace_AddOutOfOrder(t *testing.T) {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		timestamps bool
+		exp        time.Duration
+	}{
+		{true, 30 * time.Millisecond},
+		{false, 0},
+	} {
+		records := []*Record{
+			{Source: Source{Name: "web"}, Line: `{"msg":"request","time":"2017-01-01T10:00:00.050Z","request_id":"r1"}`},
+			{Source: Source{Name: "api"}, Line: `{"msg":"lookup","time":"2017-01-01T10:00:00.010Z","request_id":"r1"}`},
+		}
+		trace := NewTrace("r1")
+		for i, r := range records {
+			if tc.timestamps {
+				r.Timestamp = start.Add(time.Duration(i) * 30 * time.Millisecond)
+			}
+			r.Log = ParseLog(r.Line)
+			if !trace.Add(r) {
+				t.Fatalf("expected %s to be traced", r.Log.Msg)
+			}
+		}
+		if !records[1].Hop || records[1].Latency != tc.exp {
+			t.Errorf("timestamps=%v: expected a latency of %v, got %v", tc.timestamps, tc.exp, records[1].Latency)
+		}
+	}
+}
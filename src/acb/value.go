@@ -0,0 +1,242 @@
+package dockerlogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type ValueKind int
+
+const (
+	StringKind ValueKind = iota
+	IntKind
+	FloatKind
+	BoolKind
+	NullKind
+	ArrayKind
+	ObjectKind
+)
+
+// Value is a typed field value. Numbers keep the text they were parsed from
+// in Str, so that they are rendered and re-emitted exactly as logged.
+type Value struct {
+	Kind   ValueKind
+	Str    string
+	Int    int64
+	Float  float64
+	Bool   bool
+	Array  []Value
+	Object KeyValues
+}
+
+func StringValue(s string) Value {
+	return Value{Kind: StringKind, Str: s}
+}
+
+func IntValue(i int64) Value {
+	return Value{Kind: IntKind, Int: i, Str: strconv.FormatInt(i, 10)}
+}
+
+func FloatValue(f float64) Value {
+	return Value{Kind: FloatKind, Float: f, Str: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+func BoolValue(b bool) Value {
+	return Value{Kind: BoolKind, Bool: b}
+}
+
+func NullValue() Value {
+	return Value{Kind: NullKind}
+}
+
+func ArrayValue(a []Value) Value {
+	return Value{Kind: ArrayKind, Array: a}
+}
+
+func ObjectValue(o KeyValues) Value {
+	return Value{Kind: ObjectKind, Object: o}
+}
+
+// numberValue returns an int value if the number is integral, otherwise a
+// float value.
+func numberValue(n json.Number) (Value, error) {
+	if i, err := n.Int64(); err == nil {
+		return Value{Kind: IntKind, Int: i, Str: n.String()}, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Kind: FloatKind, Float: f, Str: n.String()}, nil
+}
+
+// Number returns the numeric value of ints, floats and strings which hold a
+// number.
+func (v Value) Number() (float64, bool) {
+	switch v.Kind {
+	case IntKind:
+		return float64(v.Int), true
+	case FloatKind:
+		return v.Float, true
+	case StringKind:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.Str), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// String renders the value for display; strings are not quoted, arrays are
+// rendered as [a b] and objects as {k:v k2:v2}.
+func (v Value) String() string {
+	switch v.Kind {
+	case StringKind, IntKind, FloatKind:
+		return v.Str
+	case BoolKind:
+		return strconv.FormatBool(v.Bool)
+	case NullKind:
+		return "null"
+	case ArrayKind:
+		var buffer bytes.Buffer
+		buffer.WriteString("[")
+		for i, x := range v.Array {
+			if i != 0 {
+				buffer.WriteString(" ")
+			}
+			buffer.WriteString(x.String())
+		}
+		buffer.WriteString("]")
+		return buffer.String()
+	case ObjectKind:
+		var buffer bytes.Buffer
+		buffer.WriteString("{")
+		for i, kv := range v.Object {
+			if i != 0 {
+				buffer.WriteString(" ")
+			}
+			buffer.WriteString(kv.Key)
+			buffer.WriteString(":")
+			buffer.WriteString(kv.Value.String())
+		}
+		buffer.WriteString("}")
+		return buffer.String()
+	default:
+		panic(fmt.Sprintf("unhandled: %v", v.Kind))
+	}
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case StringKind:
+		return json.Marshal(v.Str)
+	case IntKind, FloatKind:
+		return []byte(v.Str), nil
+	case BoolKind:
+		return json.Marshal(v.Bool)
+	case NullKind:
+		return []byte("null"), nil
+	case ArrayKind:
+		if v.Array == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(v.Array)
+	case ObjectKind:
+		return v.Object.MarshalJSON()
+	default:
+		return nil, fmt.Errorf("unhandled: %v", v.Kind)
+	}
+}
+
+// MarshalJSON encodes the key values as an object, keeping their order.
+func (s KeyValues) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, kv := range s {
+		if i != 0 {
+			buffer.WriteString(",")
+		}
+		key, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := kv.Value.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// ParseJSONValue decodes a single json document, keeping the order of object
+// keys.
+func ParseJSONValue(s string) (Value, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return Value{}, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return Value{}, fmt.Errorf("unexpected data after json value")
+	}
+	return v, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return Value{}, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := KeyValues{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return Value{}, err
+				}
+				v, err := decodeJSONValue(dec)
+				if err != nil {
+					return Value{}, err
+				}
+				obj = append(obj, KeyValue{keyTok.(string), v})
+			}
+			if _, err := dec.Token(); err != nil {
+				return Value{}, err
+			}
+			return ObjectValue(obj), nil
+		case '[':
+			arr := []Value{}
+			for dec.More() {
+				v, err := decodeJSONValue(dec)
+				if err != nil {
+					return Value{}, err
+				}
+				arr = append(arr, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return Value{}, err
+			}
+			return ArrayValue(arr), nil
+		}
+	case string:
+		return StringValue(t), nil
+	case json.Number:
+		return numberValue(t)
+	case bool:
+		return BoolValue(t), nil
+	case nil:
+		return NullValue(), nil
+	}
+	return Value{}, fmt.Errorf("unexpected json token %v", tok)
+}
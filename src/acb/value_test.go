@@ -0,0 +1,57 @@
+package dockerlogs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Ensure json values keep their types and key order through a round trip.
+func TestParseJSONValue_RoundTrip(t *testing.T) {
+	var tests = []string{
+		`{"z":1,"a":2,"m":3}`,
+		`{"n":1.50,"big":12345678901234567890,"neg":-3,"exp":1e3}`,
+		`{"o":{"z":{"y":[true,null,"s",[]]},"a":{}},"b":false}`,
+		`{"s":"quote \" and é"}`,
+		`[1,"two",3.0]`,
+	}
+	for i, s := range tests {
+		v, err := ParseJSONValue(s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, s, err)
+			continue
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, s, err)
+			continue
+		}
+		if string(out) != s {
+			t.Errorf("%d. round trip mismatch:\n  exp=%s\n  got=%s", i, s, out)
+		}
+	}
+}
+
+func TestParseJSONValue_Kinds(t *testing.T) {
+	v, err := ParseJSONValue(`{"i":3,"f":2.5,"s":"x","b":true,"n":null,"a":[1],"o":{"k":"v"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []ValueKind{IntKind, FloatKind, StringKind, BoolKind, NullKind, ArrayKind, ObjectKind}
+	for i, kv := range v.Object {
+		if kv.Value.Kind != exp[i] {
+			t.Errorf("%s: expected kind %d got %d", kv.Key, exp[i], kv.Value.Kind)
+		}
+	}
+	if f, ok := v.Object[1].Value.Number(); !ok || f != 2.5 {
+		t.Errorf("expected 2.5, got %v", f)
+	}
+	if s := v.Object[6].Value.String(); s != "{k:v}" {
+		t.Errorf("expected {k:v}, got %s", s)
+	}
+
+	for _, s := range []string{`{"a":1} x`, `{"a":`, `"a"b`} {
+		if _, err := ParseJSONValue(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
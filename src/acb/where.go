@@ -0,0 +1,122 @@
+package dockerlogs
+
+import (
+	"acb/ansi"
+	"acb/query"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Where filters records using a query expression such as
+// `level>=warn && container=~"api.*" && status>=500`, see query.Parse.
+type Where struct {
+	expr query.Expr
+}
+
+// ParseWhere parses a --where expression. Levels are compared by severity,
+// so that the values they are compared to must be valid levels.
+func ParseWhere(s string) (*Where, error) {
+	expr, err := query.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	err = query.Inspect(expr, func(c *query.Comparison) error {
+		if c.Field != "level" || c.Op == query.MATCH || c.Op == query.NOTMATCH || c.Op == query.CONTAINS {
+			return nil
+		}
+		level, err := ParseLogLevel(c.Value.Str)
+		if err != nil {
+			return fmt.Errorf("%s: %v", c, err)
+		}
+		c.Value = query.Number(float64(level))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Where{expr}, nil
+}
+
+// Match returns true if the record matches the expression.
+func (w *Where) Match(r *Record) bool {
+	return w.expr.Eval(whereRecord{r})
+}
+
+// whereRecord exposes the fields of a record to query expressions: level,
+// msg, caller, time, container, service, project, image, stream and
+// timestamp, followed by the context fields. Fields of nested objects are
+// named by their path, e.g. http.status.
+type whereRecord struct {
+	*Record
+}
+
+func (r whereRecord) Field(name string) (query.Value, bool) {
+	l := r.Log
+	switch name {
+	case "level":
+		if l.Level == UNKNOWN {
+			return query.Value{}, false
+		}
+		return query.Value{Str: l.Level.String(), Num: float64(l.Level), IsNum: true}, true
+	case "msg":
+		return query.String(ansi.Strip(l.Msg)), true
+	case "caller":
+		return stringField(l.Caller)
+	case "time":
+		return timeField(l.Time)
+	case "container":
+		return stringField(r.Source.Name)
+	case "service":
+		return stringField(r.Source.Service)
+	case "project":
+		return stringField(r.Source.Project)
+	case "image":
+		return stringField(r.Source.Image)
+	case "stream":
+		return stringField(r.Stream)
+	case "timestamp":
+		return timeField(r.Timestamp)
+	}
+
+	if v, ok := lookupField(l.Context, name); ok {
+		return queryValue(v), true
+	}
+	return query.Value{}, false
+}
+
+func stringField(s string) (query.Value, bool) {
+	return query.String(s), s != ""
+}
+
+func timeField(t time.Time) (query.Value, bool) {
+	if t.IsZero() {
+		return query.Value{}, false
+	}
+	return query.String(t.Format(time.RFC3339Nano)), true
+}
+
+// lookupField finds a field by name, or by its path through nested objects.
+func lookupField(fields KeyValues, name string) (Value, bool) {
+	for _, kv := range fields {
+		if kv.Key == name {
+			return kv.Value, true
+		}
+	}
+	for _, kv := range fields {
+		if kv.Value.Kind == ObjectKind && strings.HasPrefix(name, kv.Key+".") {
+			if v, ok := lookupField(kv.Value.Object, name[len(kv.Key)+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return Value{}, false
+}
+
+func queryValue(v Value) query.Value {
+	if v.Kind == IntKind || v.Kind == FloatKind {
+		f, _ := v.Number()
+		return query.Value{Str: v.Str, Num: f, IsNum: true}
+	}
+	return query.String(v.String())
+}
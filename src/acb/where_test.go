@@ -0,0 +1,42 @@
+package dockerlogs
+
+import (
+	"testing"
+)
+
+// Ensure --where expressions see the levels, sources and fields of records.
+func TestWhere_Match(t *testing.T) {
+	line := `{"level":"error","msg":"GET /healthcheck","status":503,"http":{"method":"GET"}}`
+	r := &Record{
+		Source: NewSource("shop_api_1", "acme/api", nil),
+		Stream: Stderr,
+		Line:   line,
+		Log:    ParseLog(line),
+	}
+	var tests = []struct {
+		s   string
+		exp bool
+	}{
+		{s: `level>=warn`, exp: true},
+		{s: `level<E`, exp: false},
+		{s: `level=~"err"`, exp: true},
+		{s: `container=~"api" && service=shop_api && image="acme/api"`, exp: true},
+		{s: `stream=stderr && status>=500`, exp: true},
+		{s: `http.method=GET`, exp: true},
+		{s: `!msg~"HEALTHCHECK"`, exp: false},
+		{s: `caller || time`, exp: false},
+	}
+	for i, tt := range tests {
+		where, err := ParseWhere(tt.s)
+		if err != nil {
+			t.Fatalf("%d. %q: %v", i, tt.s, err)
+		}
+		if got := where.Match(r); got != tt.exp {
+			t.Errorf("%d. %q: exp=%v got=%v", i, tt.s, tt.exp, got)
+		}
+	}
+
+	if _, err := ParseWhere(`level>=loud`); err == nil {
+		t.Errorf("expected unknown level error")
+	}
+}